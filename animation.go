@@ -0,0 +1,227 @@
+package engine
+
+import "time"
+
+// FrameDuration gets how long a frame of the series is shown for, which is
+// its entry in FrameDurations when one is set and otherwise an even share of
+// a cycle at the series' CyclesPerSecond (one cycle per second if unset)
+func (spriteSeries SpriteSeries) FrameDuration(frameIndex int) time.Duration {
+
+	if frameIndex < len(spriteSeries.FrameDurations) && spriteSeries.FrameDurations[frameIndex] > 0 {
+		return spriteSeries.FrameDurations[frameIndex]
+	}
+
+	if len(spriteSeries.Sprites) == 0 {
+		return 0
+	}
+
+	cyclesPerSecond := spriteSeries.CyclesPerSecond
+
+	if cyclesPerSecond <= 0 {
+		cyclesPerSecond = 1
+	}
+
+	return time.Second / time.Duration(cyclesPerSecond*len(spriteSeries.Sprites))
+}
+
+// CycleDuration gets how long it takes to play through the series once, which
+// for ping-pong series includes the way back
+func (spriteSeries SpriteSeries) CycleDuration() time.Duration {
+
+	cycleDuration := time.Duration(0)
+
+	for i := 0; i < spriteSeries.sequenceLength(); i++ {
+		cycleDuration += spriteSeries.FrameDuration(spriteSeries.sequenceFrame(i))
+	}
+
+	return cycleDuration
+}
+
+// FrameAt gets the index of the frame that is showing once the series has
+// been playing for the elapsed time
+func (spriteSeries SpriteSeries) FrameAt(elapsed time.Duration) int {
+
+	step, _ := spriteSeries.stepAt(elapsed)
+
+	return spriteSeries.frameAtStep(step)
+}
+
+// IsFinishedAt determines whether a series that doesn't loop forever has
+// finished playing after the elapsed time
+func (spriteSeries SpriteSeries) IsFinishedAt(elapsed time.Duration) bool {
+
+	_, isFinished := spriteSeries.stepAt(elapsed)
+
+	return isFinished
+}
+
+// sequenceLength gets how many frames are shown during a single cycle
+func (spriteSeries SpriteSeries) sequenceLength() int {
+
+	frameCount := len(spriteSeries.Sprites)
+
+	// Ping-pong series play back down to (but not including) the first frame
+	if spriteSeries.PlayMode == AnimationPingPong && frameCount > 2 {
+		return (frameCount * 2) - 2
+	}
+
+	return frameCount
+}
+
+// sequenceFrame gets the index of the frame shown at a position in a single
+// cycle, where ping-pong series count back down past their last frame
+func (spriteSeries SpriteSeries) sequenceFrame(position int) int {
+
+	frameCount := len(spriteSeries.Sprites)
+
+	if position < frameCount {
+		return position
+	}
+
+	return (frameCount * 2) - 2 - position
+}
+
+// cycleCount gets how many cycles the series plays for, with 0 meaning forever
+func (spriteSeries SpriteSeries) cycleCount() int {
+
+	if spriteSeries.PlayMode == AnimationOnce {
+		return 1
+	}
+
+	return spriteSeries.LoopCount
+}
+
+// stepAt gets how many frames into the series' whole playback (counting
+// across cycles) the series is after the elapsed time, and whether playback
+// has finished. Finished series rest on their final frame
+func (spriteSeries SpriteSeries) stepAt(elapsed time.Duration) (int, bool) {
+
+	sequenceLength := spriteSeries.sequenceLength()
+	cycleDuration := spriteSeries.CycleDuration()
+
+	if cycleDuration <= 0 || sequenceLength == 0 {
+		return 0, false
+	}
+
+	cycles := int(elapsed / cycleDuration)
+	cycleCount := spriteSeries.cycleCount()
+
+	if cycleCount > 0 && cycles >= cycleCount {
+
+		// Ping-pong series finish back where they started
+		if spriteSeries.PlayMode == AnimationPingPong {
+			return cycleCount * sequenceLength, true
+		}
+
+		return (cycleCount * sequenceLength) - 1, true
+	}
+
+	elapsed -= time.Duration(cycles) * cycleDuration
+
+	for i := 0; i < sequenceLength; i++ {
+
+		elapsed -= spriteSeries.FrameDuration(spriteSeries.sequenceFrame(i))
+
+		if elapsed < 0 {
+			return (cycles * sequenceLength) + i, false
+		}
+	}
+
+	return ((cycles + 1) * sequenceLength) - 1, false
+}
+
+// frameAtStep gets the frame shown at a step of the series' playback
+func (spriteSeries SpriteSeries) frameAtStep(step int) int {
+
+	sequenceLength := spriteSeries.sequenceLength()
+
+	if sequenceLength == 0 {
+		return 0
+	}
+
+	return spriteSeries.sequenceFrame(step % sequenceLength)
+}
+
+// AnimationFrame gets the index of the frame of the current state's sprite
+// series that the game object is showing. Animations restart from the first
+// frame whenever the game object's state changes
+func (gameObject *GameObject) AnimationFrame() int {
+
+	if gameObject.CurrentState != gameObject.animationState {
+		return 0
+	}
+
+	return gameObject.currentSeries().FrameAt(gameObject.animationElapsed)
+}
+
+// IsAnimationFinished determines whether the current state's animation has
+// played through, which only happens for series that don't loop forever
+func (gameObject *GameObject) IsAnimationFinished() bool {
+	return gameObject.CurrentState == gameObject.animationState && gameObject.animationFinished == true
+}
+
+// RestartAnimation starts the current state's animation again from its first
+// frame
+func (gameObject *GameObject) RestartAnimation() {
+
+	gameObject.animationState = gameObject.CurrentState
+	gameObject.animationElapsed = 0
+	gameObject.animationFinished = false
+
+	gameObject.emitFrameEvent(gameObject.currentSeries(), 0)
+}
+
+// advanceAnimation moves the game object's animation on by the elapsed time,
+// restarting it first if the game object's state has changed since it last
+// moved. Named frame events are emitted for every frame reached on the way,
+// and series that finish call their completion callback before moving on to
+// their next state
+func (gameObject *GameObject) advanceAnimation(elapsed time.Duration) {
+
+	if gameObject.CurrentState != gameObject.animationState {
+		gameObject.RestartAnimation()
+	}
+
+	if gameObject.animationFinished == true {
+		return
+	}
+
+	spriteSeries := gameObject.currentSeries()
+	previousStep, _ := spriteSeries.stepAt(gameObject.animationElapsed)
+
+	gameObject.animationElapsed += elapsed
+	step, isFinished := spriteSeries.stepAt(gameObject.animationElapsed)
+
+	for i := previousStep + 1; i <= step; i++ {
+		gameObject.emitFrameEvent(spriteSeries, spriteSeries.frameAtStep(i))
+	}
+
+	if isFinished == false {
+		return
+	}
+
+	gameObject.animationFinished = true
+	finishedState := gameObject.CurrentState
+
+	if spriteSeries.OnComplete != nil {
+		spriteSeries.OnComplete(gameObject)
+	}
+
+	// Leave the state alone if the completion callback already changed it
+	if spriteSeries.NextState != "" && gameObject.CurrentState == finishedState {
+		gameObject.ChangeState(spriteSeries.NextState)
+	}
+}
+
+// emitFrameEvent sends the named event for a frame of a series (if it has
+// one) to the game object's animation event handler
+func (gameObject *GameObject) emitFrameEvent(spriteSeries SpriteSeries, frameIndex int) {
+
+	eventName, ok := spriteSeries.FrameEvents[frameIndex]
+
+	if ok == false || gameObject.AnimationEventHandler == nil {
+		return
+	}
+
+	gameObject.AnimationEventHandler(eventName, gameObject)
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSequenceFrames(t *testing.T) {
+
+	sprite := testSprite(t)
+	expectedSequences := map[int][]int{
+		0:                 {0, 1, 2, 3},
+		AnimationPingPong: {0, 1, 2, 3, 2, 1},
+	}
+
+	for playMode, expected := range expectedSequences {
+
+		spriteSeries := SpriteSeries{
+			Sprites:  []SpriteInterface{sprite, sprite, sprite, sprite},
+			PlayMode: playMode,
+		}
+		sequence := []int{}
+
+		for i := 0; i < spriteSeries.sequenceLength(); i++ {
+			sequence = append(sequence, spriteSeries.sequenceFrame(i))
+		}
+
+		if reflect.DeepEqual(sequence, expected) == false {
+			t.Errorf("expected play mode %d to show frames %v, got %v", playMode, expected, sequence)
+		}
+	}
+}
+
+func TestAdvanceAnimationCountsTheTickAStateStarts(t *testing.T) {
+
+	sprite := testSprite(t)
+	tick := time.Second / 60
+	completions := 0
+	events := []string{}
+
+	gameObject := testObject(sprite, 0, 0)
+	gameObject.States["attack"] = SpriteSeries{
+		Sprites: []SpriteInterface{sprite, sprite, sprite},
+
+		// The second frame is shorter than a tick, so is passed mid-tick
+		FrameDurations: []time.Duration{10 * time.Millisecond, 5 * time.Millisecond, 30 * time.Millisecond},
+		PlayMode:       AnimationOnce,
+		FrameEvents:    map[int]string{0: "windup", 1: "swing", 2: "hit"},
+		NextState:      "default",
+		OnComplete: func(gameObject *GameObject) {
+			completions++
+		},
+	}
+	gameObject.AnimationEventHandler = func(eventName string, gameObject *GameObject) {
+		events = append(events, eventName)
+	}
+
+	gameObject.advanceAnimation(tick)
+	gameObject.ChangeState("attack")
+
+	// The tick the state changes in counts towards its animation
+	gameObject.advanceAnimation(tick)
+
+	if expected := []string{"windup", "swing", "hit"}; reflect.DeepEqual(events, expected) == false {
+		t.Fatalf("expected events %v after the first tick, got %v", expected, events)
+	}
+
+	if frame := gameObject.AnimationFrame(); frame != 2 {
+		t.Fatalf("expected to show frame 2 after the first tick, got %d", frame)
+	}
+
+	// 45ms of frames take 3 ticks to play through
+	gameObject.advanceAnimation(tick)
+
+	if completions != 0 || gameObject.CurrentState != "attack" {
+		t.Fatal("expected the animation to still be playing after 2 ticks")
+	}
+
+	gameObject.advanceAnimation(tick)
+
+	if completions != 1 || gameObject.CurrentState != "default" {
+		t.Fatalf("expected the animation to complete after 3 ticks, completed %d times in state %s", completions, gameObject.CurrentState)
+	}
+}
+
+func BenchmarkAdvanceAnimation(b *testing.B) {
+
+	sprite := testSprite(b)
+	gameObject := testObject(sprite, 0, 0)
+	gameObject.States["default"] = SpriteSeries{
+		Sprites:         []SpriteInterface{sprite, sprite, sprite, sprite},
+		CyclesPerSecond: 2,
+		PlayMode:        AnimationPingPong,
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		gameObject.advanceAnimation(time.Second / 60)
+	}
+}
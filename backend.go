@@ -0,0 +1,11 @@
+package engine
+
+import "context"
+
+// Backend is an interface that defines the surfaces a game can be run on. A
+// backend drives the game's frame pipeline (BeforePaint, Level.Repaint and
+// FramePainter) until it decides the game is finished or the context is
+// cancelled
+type Backend interface {
+	Run(ctx context.Context, game *Game) error
+}
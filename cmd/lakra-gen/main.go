@@ -0,0 +1,280 @@
+// Command lakra-gen generates Go source for lakra sprites from PNG images.
+//
+// A single image is generated with:
+//
+//	lakra-gen -input character.png -output character.go -package main -name Character
+//
+// and every PNG in a directory with:
+//
+//	lakra-gen -input assets -output sprites -package sprites
+//
+// in which case each file's exported name is taken from its file name.
+//
+// Sheets laid out as rows of animation frames are generated as
+// GameObjectStates when given a manifest naming the state of each row (see
+// manifest.go), either with -states or as a JSON file named after the image
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	engine "github.com/tesh254/lakra"
+)
+
+// Entrypoint to the generator
+func main() {
+
+	input := flag.String("input", "", "PNG file, or directory of PNG files, to generate from (required)")
+	output := flag.String("output", "", "Go file, or directory for a directory of images, to write (defaults to beside the input)")
+	packageName := flag.String("package", "main", "package name of the generated files")
+	name := flag.String("name", "", "exported name of the generated sprites (defaults to one based on the file name)")
+	paletteFile := flag.String("palette", "", "JSON file mapping palette slots to colours, such as {\"0\": \"#00000000\", \"1\": \"#ff0000\"}")
+	tileSize := flag.String("tile", "16", "size of each sprite, such as 16 or 24x32")
+	frameSize := flag.String("frame", "", "size of each animation frame, such as 32x48 (defaults to the whole image)")
+	manifestFile := flag.String("states", "", "JSON manifest naming the animation state of each row of frames (defaults to a JSON file named after the image, if there is one)")
+	importPath := flag.String("import", engine.DefaultEngineImportPath, "import path of the engine in generated files")
+	importAlias := flag.String("alias", "engine", "name the engine is imported as in generated files")
+	quantise := flag.Bool("quantise", false, "reduce images to 16 colours (one of them transparent if any pixels are), or to the -palette colours, instead of rejecting them")
+	dither := flag.Bool("dither", false, "use ordered dithering when quantising")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: lakra-gen -input <png or directory> [flags]\n\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *input == "" || flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	options, err := getOptions(*paletteFile, *tileSize, *frameSize, *importPath, *importAlias)
+
+	if err != nil {
+		exit(err)
+	}
+
+	if *dither == true && *quantise == false {
+		exit(errors.New("-dither can only be used with -quantise"))
+	}
+
+	options.Quantise = *quantise
+	options.Dither = *dither
+
+	if token.IsIdentifier(*packageName) == false {
+		exit(errors.New("Package name " + strconv.Quote(*packageName) + " is not a valid Go identifier"))
+	}
+
+	info, err := os.Stat(*input)
+
+	if err != nil {
+		exit(err)
+	}
+
+	if info.IsDir() == false {
+
+		if err := generate(*input, *output, *packageName, *name, *manifestFile, options); err != nil {
+			exit(err)
+		}
+
+		return
+	}
+
+	if *name != "" {
+		exit(errors.New("-name can't be used with a directory of images, as each file is named after itself"))
+	}
+
+	if err := generateDirectory(*input, *output, *packageName, *manifestFile, options); err != nil {
+		exit(err)
+	}
+}
+
+// getOptions builds the generator options from the command-line flags
+func getOptions(paletteFile string, tileSize string, frameSize string, importPath string, importAlias string) (engine.GeneratorOptions, error) {
+
+	options := engine.GeneratorOptions{
+		ImportPath:  importPath,
+		ImportAlias: importAlias,
+	}
+
+	if token.IsIdentifier(importAlias) == false {
+		return options, errors.New("Import alias " + strconv.Quote(importAlias) + " is not a valid Go identifier")
+	}
+
+	var err error
+
+	if options.TileWidth, options.TileHeight, err = parseSize(tileSize); err != nil {
+		return options, errors.New("Invalid -tile: " + err.Error())
+	}
+
+	if frameSize != "" {
+		if options.FrameWidth, options.FrameHeight, err = parseSize(frameSize); err != nil {
+			return options, errors.New("Invalid -frame: " + err.Error())
+		}
+	}
+
+	if paletteFile != "" {
+		if options.Palette, err = loadPalette(paletteFile); err != nil {
+			return options, err
+		}
+	}
+
+	return options, nil
+}
+
+// generateDirectory generates a file for every PNG in a directory, carrying on
+// past failures so that they can all be reported at once
+func generateDirectory(inputDirectory string, outputDirectory string, packageName string, manifestFile string, options engine.GeneratorOptions) error {
+
+	if outputDirectory == "" {
+		outputDirectory = inputDirectory
+	}
+
+	if err := os.MkdirAll(outputDirectory, 0755); err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(inputDirectory)
+
+	if err != nil {
+		return err
+	}
+
+	generated := 0
+	failed := 0
+
+	for _, file := range files {
+
+		if file.IsDir() == true || strings.EqualFold(filepath.Ext(file.Name()), ".png") == false {
+			continue
+		}
+
+		inputFile := filepath.Join(inputDirectory, file.Name())
+		outputFile := filepath.Join(outputDirectory, outputFileName(file.Name()))
+
+		if err := generate(inputFile, outputFile, packageName, "", manifestFile, options); err != nil {
+			fmt.Fprintln(os.Stderr, "lakra-gen: "+err.Error())
+			failed++
+			continue
+		}
+
+		generated++
+	}
+
+	if failed > 0 {
+		return errors.New(strconv.Itoa(failed) + " of " + strconv.Itoa(failed+generated) + " images could not be generated")
+	}
+
+	if generated == 0 {
+		return errors.New("No PNG files found in " + inputDirectory)
+	}
+
+	return nil
+}
+
+// generate generates a single file, naming its output and exported sprites
+// after the input file if they haven't been given
+func generate(inputFile string, outputFile string, packageName string, name string, manifestFile string, options engine.GeneratorOptions) error {
+
+	if outputFile == "" {
+		outputFile = filepath.Join(filepath.Dir(inputFile), outputFileName(filepath.Base(inputFile)))
+	}
+
+	if name == "" {
+		name = exportedName(filepath.Base(inputFile))
+	}
+
+	if token.IsIdentifier(name) == false {
+		return errors.New(inputFile + ": exported name " + strconv.Quote(name) + " is not a valid Go identifier")
+	}
+
+	options, err := withManifest(options, manifestFor(inputFile, manifestFile))
+
+	if err != nil {
+		return errors.New(inputFile + ": " + err.Error())
+	}
+
+	options.OnQuantise = func(report engine.QuantisationReport) {
+		fmt.Printf("%s: quantised %d colours to %d (mean error %.2f, max error %.2f)\n", inputFile, report.OriginalColours, report.Colours, report.MeanError, report.MaxError)
+	}
+
+	if err := engine.GenerateFromPNGFileWithOptions(inputFile, outputFile, packageName, name, options); err != nil {
+		return errors.New(inputFile + ": " + err.Error())
+	}
+
+	return nil
+}
+
+// outputFileName gets the name of the Go file generated from an image file
+func outputFileName(imageFileName string) string {
+	return strings.TrimSuffix(imageFileName, filepath.Ext(imageFileName)) + ".go"
+}
+
+// exportedName turns an image file name like "character-moving.png" into an
+// exported Go name like "CharacterMoving"
+func exportedName(imageFileName string) string {
+
+	words := strings.FieldsFunc(strings.TrimSuffix(imageFileName, filepath.Ext(imageFileName)), func(r rune) bool {
+		return unicode.IsLetter(r) == false && unicode.IsDigit(r) == false
+	})
+
+	name := ""
+
+	for _, word := range words {
+		name += strings.ToUpper(word[:1]) + word[1:]
+	}
+
+	if name == "" || unicode.IsDigit(rune(name[0])) == true {
+		name = "Sprite" + name
+	}
+
+	return name
+}
+
+// parseSize parses a size given as either a single number or a width and
+// height like 24x32
+func parseSize(size string) (int, int, error) {
+
+	parts := strings.Split(strings.ToLower(size), "x")
+
+	if len(parts) > 2 {
+		return 0, 0, errors.New(strconv.Quote(size) + " should be a number or a size like 24x32")
+	}
+
+	width, err := strconv.Atoi(parts[0])
+
+	if err != nil {
+		return 0, 0, errors.New(strconv.Quote(size) + " should be a number or a size like 24x32")
+	}
+
+	height := width
+
+	if len(parts) == 2 {
+		if height, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, errors.New(strconv.Quote(size) + " should be a number or a size like 24x32")
+		}
+	}
+
+	if width <= 0 || height <= 0 {
+		return 0, 0, errors.New(strconv.Quote(size) + " should be greater than 0")
+	}
+
+	return width, height, nil
+}
+
+// exit reports an error and exits with a non-zero status
+func exit(err error) {
+
+	fmt.Fprintln(os.Stderr, "lakra-gen: "+err.Error())
+	os.Exit(1)
+}
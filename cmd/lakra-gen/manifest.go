@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	engine "github.com/tesh254/lakra"
+)
+
+// manifest is a struct that defines the animation states laid out as rows of a
+// sprite sheet, such as:
+//
+//	{
+//		"frameWidth": 32,
+//		"frameHeight": 48,
+//		"states": [
+//			{"name": "standing", "cyclesPerSecond": 1, "frames": 1},
+//			{"name": "moving", "cyclesPerSecond": 2}
+//		]
+//	}
+type manifest struct {
+	FrameWidth  int                     `json:"frameWidth"`
+	FrameHeight int                     `json:"frameHeight"`
+	States      []engine.GeneratedState `json:"states"`
+}
+
+// loadManifest reads a state manifest from a JSON file
+func loadManifest(manifestFile string) (*manifest, error) {
+
+	contents, err := ioutil.ReadFile(manifestFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stateManifest := &manifest{}
+
+	if err := json.Unmarshal(contents, stateManifest); err != nil {
+		return nil, errors.New("Error reading manifest file " + manifestFile + ": " + err.Error())
+	}
+
+	if len(stateManifest.States) == 0 {
+		return nil, errors.New("Manifest file " + manifestFile + " doesn't name any states")
+	}
+
+	return stateManifest, nil
+}
+
+// manifestFor gets the manifest file for an image, which is the one given on
+// the command line or else a JSON file named after the image beside it (if
+// there is one)
+func manifestFor(inputFile string, manifestFile string) string {
+
+	if manifestFile != "" {
+		return manifestFile
+	}
+
+	sidecarFile := strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + ".json"
+
+	if _, err := os.Stat(sidecarFile); err != nil {
+		return ""
+	}
+
+	return sidecarFile
+}
+
+// withManifest gets the generator options for an image laid out as in a
+// manifest, with frame sizes given on the command line taking precedence
+func withManifest(options engine.GeneratorOptions, manifestFile string) (engine.GeneratorOptions, error) {
+
+	if manifestFile == "" {
+		return options, nil
+	}
+
+	stateManifest, err := loadManifest(manifestFile)
+
+	if err != nil {
+		return options, err
+	}
+
+	if options.FrameWidth == 0 && options.FrameHeight == 0 {
+		options.FrameWidth = stateManifest.FrameWidth
+		options.FrameHeight = stateManifest.FrameHeight
+	}
+
+	options.States = stateManifest.States
+
+	return options, nil
+}
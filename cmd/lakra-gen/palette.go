@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"image/color"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	engine "github.com/tesh254/lakra"
+)
+
+// loadPalette reads a palette from a JSON file mapping slots ("0" to "f") to
+// colours written as #rrggbb or #rrggbbaa
+func loadPalette(paletteFile string) (*engine.Palette, error) {
+
+	contents, err := ioutil.ReadFile(paletteFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	colours := map[string]string{}
+
+	if err := json.Unmarshal(contents, &colours); err != nil {
+		return nil, errors.New("Error reading palette file " + paletteFile + ": " + err.Error())
+	}
+
+	palette := engine.Palette{}
+
+	for slot, hexColour := range colours {
+
+		slot = strings.ToLower(slot)
+
+		if len(slot) != 1 || strings.Contains("0123456789abcdef", slot) == false {
+			return nil, errors.New("Palette file " + paletteFile + " has slot " + strconv.Quote(slot) + ", but slots must be 0 to f")
+		}
+
+		colour, err := parseHexColour(hexColour)
+
+		if err != nil {
+			return nil, errors.New("Palette file " + paletteFile + " slot " + slot + ": " + err.Error())
+		}
+
+		palette[slot] = colour
+	}
+
+	return &palette, nil
+}
+
+// parseHexColour parses a colour written as #rrggbb or #rrggbbaa, with colours
+// that leave out their alpha being opaque. Colours are written the way image
+// editors show them, so are premultiplied by their alpha to match the pixels
+// of images they are checked against
+func parseHexColour(hexColour string) (color.RGBA, error) {
+
+	digits := strings.TrimPrefix(hexColour, "#")
+
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+
+	if len(digits) != 8 || err != nil {
+		return color.RGBA{}, errors.New(strconv.Quote(hexColour) + " is not a colour like #rrggbb or #rrggbbaa")
+	}
+
+	colour := color.NRGBA{uint8(value >> 24), uint8(value >> 16), uint8(value >> 8), uint8(value)}
+
+	return color.RGBAModel.Convert(colour).(color.RGBA), nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	engine "github.com/tesh254/lakra"
+)
+
+func TestSemiTransparentPaletteColoursMatchTheirPixels(t *testing.T) {
+
+	colour, err := parseHexColour("#ff000080")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	img.SetNRGBA(4, 4, color.NRGBA{255, 0, 0, 128})
+
+	palette := engine.Palette{"0": color.RGBA{0, 0, 0, 0}, "1": colour}
+
+	if _, err := engine.GenerateFromImage(img, "assets", "Glass", palette); err != nil {
+		t.Fatalf("expected a semi-transparent pixel to match its palette colour: %v", err)
+	}
+
+	// Missing colours are listed as they were drawn
+	img.SetNRGBA(5, 4, color.NRGBA{0, 255, 0, 128})
+	_, err = engine.GenerateFromImage(img, "assets", "Glass", palette)
+
+	if err == nil || strings.Contains(err.Error(), "(5,4) #00ff0080") == false {
+		t.Fatalf("expected the missing pixel to be listed as #00ff0080, got %v", err)
+	}
+}
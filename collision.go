@@ -0,0 +1,162 @@
+package engine
+
+import "math"
+
+// bounds is a struct that defines an axis-aligned box in level coordinates,
+// where Y is the bottom edge of the box
+type bounds struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// bounds gets the box the game object's hitbox currently occupies
+func (gameObject *GameObject) bounds() bounds {
+	return gameObject.boundsAt(gameObject.Position)
+}
+
+// boundsAt gets the box the game object's hitbox would occupy at a position
+func (gameObject *GameObject) boundsAt(position Vector) bounds {
+
+	hitbox := gameObject.CurrentHitbox()
+
+	return bounds{
+		X:      position.X + hitbox.Offset.X,
+		Y:      position.Y + hitbox.Offset.Y,
+		Width:  hitbox.Width,
+		Height: hitbox.Height,
+	}
+}
+
+// SweepMovement checks the path a game object took during the last tick
+// against every floor and solid object, stopping it at the first one it
+// would have passed through. This stops fast objects tunnelling through thin
+// objects that a check of their start and end positions alone would miss.
+// Floors only block objects coming down onto them, while solid objects block
+// from every side
+func (level *Level) SweepMovement(gameObject *GameObject) {
+
+	start := gameObject.PreviousPosition
+
+	// An object can hit at most one obstacle per axis
+	for i := 0; i < 2; i++ {
+
+		delta := Vector{
+			X: gameObject.Position.X - start.X,
+			Y: gameObject.Position.Y - start.Y,
+		}
+
+		if delta.X == 0 && delta.Y == 0 {
+			return
+		}
+
+		moving := gameObject.boundsAt(start)
+
+		var hitObject *GameObject
+		hitTime := 1.0
+		hitEdge := EdgeNone
+
+		// Only objects within the area swept out by the movement can be hit
+		swept := bounds{
+			X:      math.Min(moving.X, moving.X+delta.X),
+			Y:      math.Min(moving.Y, moving.Y+delta.Y),
+			Width:  math.Abs(delta.X) + moving.Width,
+			Height: math.Abs(delta.Y) + moving.Height,
+		}
+
+		for _, obstacle := range level.index().query(swept) {
+
+			if obstacle == gameObject || obstacle.IsHidden == true || obstacle.IsInteractive == false || (obstacle.IsFloor == false && obstacle.IsSolid == false) || gameObject.CollidesWith(obstacle) == false {
+				continue
+			}
+
+			timeOfImpact, edge, ok := sweepBounds(moving, delta, obstacle.bounds())
+
+			if ok == false || timeOfImpact >= hitTime {
+				continue
+			}
+
+			// Floors that aren't solid can be passed through from below or
+			// the side
+			if obstacle.IsSolid == false && edge != EdgeTop {
+				continue
+			}
+
+			hitObject = obstacle
+			hitTime = timeOfImpact
+			hitEdge = edge
+		}
+
+		if hitObject == nil {
+			return
+		}
+
+		gameObject.pushOutOf(hitObject, hitEdge)
+
+		gameObject.CollisionHandler(gameObject, Collision{
+			GameObject:   hitObject,
+			Edge:         hitEdge,
+			TimeOfImpact: hitTime,
+		})
+
+		// Carry on along the remaining axis from the point of impact
+		start = Vector{
+			X: start.X + (delta.X * hitTime),
+			Y: start.Y + (delta.Y * hitTime),
+		}
+	}
+}
+
+// sweepBounds finds the fraction of a movement at which a moving box first
+// touches a static box, along with the edge of the moving box's position
+// relative to the static one at that moment. Boxes that already overlap are
+// not reported, as they are resolved by ResolveSolidCollisions
+func sweepBounds(moving bounds, delta Vector, target bounds) (float64, string, bool) {
+
+	entryX, exitX := sweepAxis(moving.X, moving.Width, delta.X, target.X, target.Width)
+	entryY, exitY := sweepAxis(moving.Y, moving.Height, delta.Y, target.Y, target.Height)
+
+	entry := math.Max(entryX, entryY)
+	exit := math.Min(exitX, exitY)
+
+	if entry >= exit || entry < 0 || entry > 1 {
+		return 0, EdgeNone, false
+	}
+
+	if entryX > entryY {
+
+		if delta.X > 0 {
+			return entry, EdgeLeft, true
+		}
+
+		return entry, EdgeRight, true
+	}
+
+	if delta.Y > 0 {
+		return entry, EdgeBottom, true
+	}
+
+	return entry, EdgeTop, true
+}
+
+// sweepAxis finds the fractions of a movement along a single axis at which a
+// moving span starts and stops overlapping a static span
+func sweepAxis(position float64, size float64, delta float64, targetPosition float64, targetSize float64) (float64, float64) {
+
+	if delta == 0 {
+
+		// Without movement the spans either always or never overlap
+		if position < targetPosition+targetSize && position+size > targetPosition {
+			return math.Inf(-1), math.Inf(1)
+		}
+
+		return math.Inf(1), math.Inf(-1)
+	}
+
+	if delta > 0 {
+		return (targetPosition - (position + size)) / delta, ((targetPosition + targetSize) - position) / delta
+	}
+
+	return ((targetPosition + targetSize) - position) / delta, (targetPosition - (position + size)) / delta
+}
@@ -0,0 +1,221 @@
+package engine
+
+import "testing"
+
+func TestHitboxesFollowFlips(t *testing.T) {
+
+	// The hitbox covers the opaque right half of the sprite
+	gameObject := testObject(testSprite(t), 0, 0)
+	gameObject.Hitbox = &Hitbox{Offset: Vector{X: 8, Y: 2}, Width: 8, Height: 4}
+
+	expectedBounds := []struct {
+		isFlipped           bool
+		isFlippedVertically bool
+		bounds              bounds
+	}{
+		{false, false, bounds{X: 8, Y: 2, Width: 8, Height: 4}},
+		{true, false, bounds{X: 0, Y: 2, Width: 8, Height: 4}},
+		{false, true, bounds{X: 8, Y: 10, Width: 8, Height: 4}},
+		{true, true, bounds{X: 0, Y: 10, Width: 8, Height: 4}},
+	}
+
+	for _, expected := range expectedBounds {
+
+		gameObject.IsFlipped = expected.isFlipped
+		gameObject.IsFlippedVertically = expected.isFlippedVertically
+
+		if actual := gameObject.bounds(); actual != expected.bounds {
+			t.Errorf("expected bounds %v when flipped %v and flipped vertically %v, got %v", expected.bounds, expected.isFlipped, expected.isFlippedVertically, actual)
+		}
+	}
+
+	// Facing left, the opaque half and its hitbox are both on the left
+	gameObject.IsFlipped = true
+	gameObject.IsFlippedVertically = false
+	gameObject.IsPixelPerfect = true
+	otherObject := testObject(testSprite(t), -12, 0)
+
+	if gameObject.pixelsOverlap(otherObject) == false {
+		t.Fatal("expected the mirrored hitbox to clip the mirrored sprite mask")
+	}
+}
+
+func TestHitboxesFollowRotationAndScale(t *testing.T) {
+
+	// A 16x16 sprite with a hitbox over a 4x2 area near its bottom left
+	gameObject := testObject(testSprite(t), 0, 0)
+	gameObject.Hitbox = &Hitbox{Offset: Vector{X: 1, Y: 2}, Width: 4, Height: 2}
+
+	expectedBounds := []struct {
+		rotation int
+		scale    int
+		bounds   bounds
+	}{
+		{0, 2, bounds{X: 2, Y: 4, Width: 8, Height: 4}},
+		{90, 1, bounds{X: 2, Y: 11, Width: 2, Height: 4}},
+		{180, 1, bounds{X: 11, Y: 12, Width: 4, Height: 2}},
+		{270, 1, bounds{X: 12, Y: 1, Width: 2, Height: 4}},
+		{-90, 2, bounds{X: 24, Y: 2, Width: 4, Height: 8}},
+	}
+
+	for _, expected := range expectedBounds {
+
+		gameObject.Rotation = expected.rotation
+		gameObject.Scale = expected.scale
+
+		if actual := gameObject.bounds(); actual != expected.bounds {
+			t.Errorf("expected bounds %v when rotated %d and scaled %d, got %v", expected.bounds, expected.rotation, expected.scale, actual)
+		}
+	}
+}
+
+func TestHitboxesMatchRotatedPixels(t *testing.T) {
+
+	// The sprite is only opaque across its right half, so its hitbox is too
+	gameObject := testObject(testSprite(t), 0, 0)
+	gameObject.Hitbox = &Hitbox{Offset: Vector{X: 8}, Width: 8, Height: 16}
+
+	for _, rotation := range []int{0, 90, 180, 270} {
+
+		gameObject.Rotation = rotation
+		gameObject.Scale = 2
+		mask := getSpriteMask(gameObject.CurrentSprite(), gameObject.DrawOptions())
+		box := gameObject.bounds()
+
+		for y := 0; y < gameObject.Height(); y++ {
+			for x := 0; x < gameObject.Width(); x++ {
+
+				inHitbox := float64(x) >= box.X && float64(x) < box.X+box.Width && float64(y) >= box.Y && float64(y) < box.Y+box.Height
+
+				if gameObject.isOpaqueAt(mask, x, y) != inHitbox {
+					t.Fatalf("expected the hitbox to cover the opaque pixels when rotated %d, they differ at (%d,%d)", rotation, x, y)
+				}
+			}
+		}
+	}
+}
+
+// thinSprite creates a sprite of the given size that is opaque all over
+func thinSprite(tb testing.TB, width int, height int) *Sprite {
+
+	scanlines := []int{}
+
+	for i := 0; i < scanlineGroupsPerRow(width)*height; i++ {
+		scanlines = append(scanlines, 0x11111111)
+	}
+
+	sprite, err := CreateSpriteWithSize(testPalette, width, height, scanlines)
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return sprite
+}
+
+// sweep moves a game object from one position to another within a level of
+// obstacles and sweeps the movement, returning the collisions it handled
+func sweep(gameObject *GameObject, from Vector, to Vector, obstacles ...*GameObject) []Collision {
+
+	collisions := []Collision{}
+	gameObject.CollisionHandler = func(gameObject *GameObject, collision Collision) {
+		collisions = append(collisions, collision)
+	}
+
+	level := &Level{GameObjects: append(obstacles, gameObject)}
+	gameObject.PreviousPosition = from
+	gameObject.Position = to
+	level.SweepMovement(gameObject)
+
+	return collisions
+}
+
+func TestSweepStopsFastFallOntoThinFloor(t *testing.T) {
+
+	floor := testObject(thinSprite(t, 64, 1), 0, 100)
+	floor.IsFloor = true
+
+	// Falling 80 pixels in a tick would skip straight over the floor
+	faller := testObject(testSprite(t), 8, 150)
+	faller.Mass = 1
+	faller.Velocity.Y = -80
+	collisions := sweep(faller, Vector{X: 8, Y: 150}, Vector{X: 8, Y: 70}, floor)
+
+	if faller.Position != (Vector{X: 8, Y: 101}) || faller.Velocity.Y != 0 {
+		t.Fatalf("expected the faller to stop on top of the floor at Y 101, it is at %v moving %v", faller.Position, faller.Velocity)
+	}
+
+	if len(collisions) != 1 || collisions[0].GameObject != floor || collisions[0].Edge != EdgeTop || collisions[0].TimeOfImpact != 49.0/80 {
+		t.Fatalf("expected a single collision with the floor's top at 49/80 of the way, got %+v", collisions)
+	}
+}
+
+func TestSweepStopsFastMoveIntoThinSolid(t *testing.T) {
+
+	wall := testObject(thinSprite(t, 1, 16), 50, 0)
+	wall.IsSolid = true
+
+	mover := testObject(testSprite(t), 0, 0)
+	mover.Direction = DirRight
+	mover.Velocity.X = 100
+	collisions := sweep(mover, Vector{X: 0, Y: 0}, Vector{X: 100, Y: 0}, wall)
+
+	if mover.Position != (Vector{X: 34, Y: 0}) || mover.Direction != DirStationary {
+		t.Fatalf("expected the mover to stop against the wall at X 34, it is at %v facing %d", mover.Position, mover.Direction)
+	}
+
+	if len(collisions) != 1 || collisions[0].GameObject != wall || collisions[0].Edge != EdgeLeft || collisions[0].TimeOfImpact != 0.34 {
+		t.Fatalf("expected a single collision with the wall's left at 0.34 of the way, got %+v", collisions)
+	}
+}
+
+func TestSweepEdgeCases(t *testing.T) {
+
+	// A floor whose top is at Y 30
+	floor := testObject(thinSprite(t, 200, 16), 0, 14)
+	floor.IsFloor = true
+
+	// Landing part way through a diagonal move slides along the floor
+	mover := testObject(testSprite(t), 0, 0)
+	collisions := sweep(mover, Vector{X: 0, Y: 50}, Vector{X: 40, Y: 10}, floor)
+
+	if mover.Position != (Vector{X: 40, Y: 30}) || len(collisions) != 1 || collisions[0].Edge != EdgeTop || collisions[0].TimeOfImpact != 0.5 {
+		t.Fatalf("expected to land half way and slide to (40,30), got %v with %+v", mover.Position, collisions)
+	}
+
+	// Moving down from resting on the floor hits it straight away
+	collisions = sweep(mover, Vector{X: 0, Y: 30}, Vector{X: 0, Y: 20}, floor)
+
+	if mover.Position.Y != 30 || len(collisions) != 1 || collisions[0].TimeOfImpact != 0 {
+		t.Fatalf("expected a resting object to be stopped at once, got %v with %+v", mover.Position, collisions)
+	}
+
+	// Only just reaching the floor at the end of the move isn't a collision
+	collisions = sweep(mover, Vector{X: 0, Y: 40}, Vector{X: 0, Y: 30}, floor)
+
+	if mover.Position.Y != 30 || len(collisions) != 0 {
+		t.Fatalf("expected a move ending on the floor not to collide, got %v with %+v", mover.Position, collisions)
+	}
+
+	// Floors that aren't solid can be jumped up through
+	collisions = sweep(mover, Vector{X: 0, Y: -20}, Vector{X: 0, Y: 40}, floor)
+
+	if mover.Position.Y != 40 || len(collisions) != 0 {
+		t.Fatalf("expected to jump up through the floor, got %v with %+v", mover.Position, collisions)
+	}
+
+	// Solid objects stop objects jumping up into them
+	floor.IsSolid = true
+	collisions = sweep(mover, Vector{X: 0, Y: -20}, Vector{X: 0, Y: 40}, floor)
+
+	if mover.Position.Y != -2 || len(collisions) != 1 || collisions[0].Edge != EdgeBottom {
+		t.Fatalf("expected to hit the bottom of a solid object at Y -2, got %v with %+v", mover.Position, collisions)
+	}
+
+	// Objects already inside each other are left to ResolveSolidCollisions
+	collisions = sweep(mover, Vector{X: 0, Y: 20}, Vector{X: 0, Y: 10}, floor)
+
+	if mover.Position.Y != 10 || len(collisions) != 0 {
+		t.Fatalf("expected overlapping objects to be skipped, got %v with %+v", mover.Position, collisions)
+	}
+}
@@ -12,11 +12,42 @@ const (
 	DirStationary = 0  // x-axis
 )
 
+// Simulation constants
+const (
+	DefaultTickRate  = 60 // ticks per second when a game doesn't set one
+	MaxTicksPerFrame = 5  // ticks run before a frame is painted regardless
+
+	SpatialHashCellSize = 64 // pixel size of the cells levels index objects in
+)
+
+// Animation play modes
+const (
+	AnimationLoop     = 0 // repeat forever, or LoopCount times if set
+	AnimationOnce     = 1 // play through once and rest on the last frame
+	AnimationPingPong = 2 // play forwards then backwards, LoopCount times if set
+)
+
+// DefaultEngineImportPath is the import path generated files use for the
+// engine unless told otherwise
+const DefaultEngineImportPath = "github.com/tesh254/lakra"
+
+// AnyState is used as the From state of transitions that are allowed from
+// every state of a state machine
+const AnyState = "*"
+
 // Event constants
 const (
-	EventFloorCollision = 0
-	EventDropOffLevel   = 1
-	EventFreeFall       = 2
+	EventFloorCollision   = 0
+	EventDropOffLevel     = 1
+	EventFreeFall         = 2
+	EventWallCollision    = 3
+	EventCeilingCollision = 4
+)
+
+// Collision layers
+const (
+	CollisionLayerDefault = 1 << 0     // layer for objects that don't set one
+	CollisionMaskAll      = 0xffffffff // mask for objects that collide with every layer
 )
 
 // Collision Edges
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"image/color"
+	"math"
+)
+
+// DrawOptions is a struct that defines how a sprite is transformed as it is
+// drawn. Flips are applied first, then the clockwise rotation (in multiples of
+// 90 degrees) and finally the integer scale, where 0 and 1 leave the sprite at
+// its original size. Colours are taken from Palette in place of the sprite's
+// own palette when it is set, with PaletteRemap drawing one slot in the colour
+// of another and PaletteFilter adjusting every colour last of all
+type DrawOptions struct {
+	FlipHorizontal bool
+	FlipVertical   bool
+	Rotation       int
+	Scale          int
+	Palette        *Palette
+	PaletteRemap   map[string]string
+	PaletteFilter  PaletteFilter
+}
+
+// spriteTransform is a struct that defines the geometric part of a set of
+// draw options, normalised so that equivalent transforms compare equal
+type spriteTransform struct {
+	flipHorizontal bool
+	flipVertical   bool
+	rotation       int
+	scale          int
+}
+
+// transform gets the normalised geometric transform of the options, with the
+// rotation brought into the range 0-270 and the scale set to at least 1
+func (options DrawOptions) transform() spriteTransform {
+
+	transform := spriteTransform{
+		flipHorizontal: options.FlipHorizontal,
+		flipVertical:   options.FlipVertical,
+		rotation:       (((options.Rotation / 90) % 4) + 4) % 4 * 90,
+		scale:          options.Scale,
+	}
+
+	if transform.scale < 1 {
+		transform.scale = 1
+	}
+
+	return transform
+}
+
+// Size gets the size of a width and height once transformed by the options
+func (options DrawOptions) Size(width int, height int) (int, int) {
+	return options.transform().size(width, height)
+}
+
+// colours gets the 16 colours a sprite with the given palette is drawn in,
+// after any palette override, remapping and filtering
+func (options DrawOptions) colours(palette *Palette) [16]color.RGBA {
+
+	colours := [16]color.RGBA{}
+
+	if options.Palette != nil {
+		palette = options.Palette
+	}
+
+	if palette == nil {
+		return colours
+	}
+
+	for i, slot := range paletteSlots {
+
+		if remappedSlot, ok := options.PaletteRemap[slot]; ok {
+			slot = remappedSlot
+		}
+
+		colours[i] = (*palette)[slot]
+
+		if options.PaletteFilter != nil {
+			colours[i] = options.PaletteFilter(colours[i])
+		}
+	}
+
+	return colours
+}
+
+// size gets the size of a width and height once transformed
+func (transform spriteTransform) size(width int, height int) (int, int) {
+
+	if transform.rotation == 90 || transform.rotation == 270 {
+		width, height = height, width
+	}
+
+	return width * transform.scale, height * transform.scale
+}
+
+// point maps a pixel of an untransformed width by height image to where it
+// lands once transformed, before scaling
+func (transform spriteTransform) point(x int, y int, width int, height int) (int, int) {
+
+	if transform.flipHorizontal == true {
+		x = width - 1 - x
+	}
+
+	if transform.flipVertical == true {
+		y = height - 1 - y
+	}
+
+	switch transform.rotation {
+	case 90:
+		return height - 1 - y, x
+	case 180:
+		return width - 1 - x, height - 1 - y
+	case 270:
+		return y, width - 1 - x
+	}
+
+	return x, y
+}
+
+// rect maps an area of an untransformed width by height image to where it
+// lands once transformed, including scaling, returning its new top left corner
+func (transform spriteTransform) rect(x int, y int, rectWidth int, rectHeight int, width int, height int) (int, int) {
+
+	// The transformed area's top left corner is the lowest of the
+	// transformed corners
+	x1, y1 := transform.point(x, y, width, height)
+	x2, y2 := transform.point(x+rectWidth-1, y+rectHeight-1, width, height)
+
+	if x2 < x1 {
+		x1 = x2
+	}
+
+	if y2 < y1 {
+		y1 = y2
+	}
+
+	return x1 * transform.scale, y1 * transform.scale
+}
+
+// hitbox maps a hitbox of an untransformed width by height sprite, measured
+// from the sprite's bottom left corner, to where it lands once the sprite is
+// transformed. Rotations are clockwise as drawn, with Y counting up
+func (transform spriteTransform) hitbox(hitbox Hitbox, width float64, height float64) Hitbox {
+
+	if transform.flipHorizontal == true {
+		hitbox.Offset.X = width - hitbox.Offset.X - hitbox.Width
+	}
+
+	if transform.flipVertical == true {
+		hitbox.Offset.Y = height - hitbox.Offset.Y - hitbox.Height
+	}
+
+	switch transform.rotation {
+	case 90:
+		hitbox = Hitbox{
+			Offset: Vector{X: hitbox.Offset.Y, Y: width - hitbox.Offset.X - hitbox.Width},
+			Width:  hitbox.Height,
+			Height: hitbox.Width,
+		}
+	case 180:
+		hitbox.Offset = Vector{X: width - hitbox.Offset.X - hitbox.Width, Y: height - hitbox.Offset.Y - hitbox.Height}
+	case 270:
+		hitbox = Hitbox{
+			Offset: Vector{X: height - hitbox.Offset.Y - hitbox.Height, Y: hitbox.Offset.X},
+			Width:  hitbox.Height,
+			Height: hitbox.Width,
+		}
+	}
+
+	scale := float64(transform.scale)
+
+	return Hitbox{
+		Offset: Vector{X: hitbox.Offset.X * scale, Y: hitbox.Offset.Y * scale},
+		Width:  hitbox.Width * scale,
+		Height: hitbox.Height * scale,
+	}
+}
+
+// FlashFilter creates a palette filter that draws every visible colour as a
+// single colour, such as a white flash when an object is hit
+func FlashFilter(flashColour color.RGBA) PaletteFilter {
+
+	return func(colour color.RGBA) color.RGBA {
+
+		if colour.A == 0 {
+			return colour
+		}
+
+		return flashColour
+	}
+}
+
+// FadeFilter creates a palette filter that blends every visible colour
+// towards a target colour, where an amount of 0 leaves colours unchanged and 1
+// replaces them with the target
+func FadeFilter(target color.RGBA, amount float64) PaletteFilter {
+
+	amount = math.Max(0, math.Min(1, amount))
+	blend := func(from uint8, to uint8) uint8 {
+		return uint8(math.Round(float64(from) + ((float64(to) - float64(from)) * amount)))
+	}
+
+	return func(colour color.RGBA) color.RGBA {
+
+		if colour.A == 0 {
+			return colour
+		}
+
+		return color.RGBA{
+			R: blend(colour.R, target.R),
+			G: blend(colour.G, target.G),
+			B: blend(colour.B, target.B),
+			A: blend(colour.A, target.A),
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
+	"log"
 	"math/rand"
+	"time"
 
 	engine "github.com/tesh254/lakra"
 	"golang.org/x/image/font"
@@ -67,10 +70,15 @@ func getLevel() *engine.Level {
 func main() {
 
 	levels := []*engine.Level{
-		getLevel(),
+		engine.NewLevelFromFactory(getLevel),
 	}
 
-	engine.CreateGame("Lakra Game Prototype", 320, 224, 2, 64, framePainter, keyListener, levels)
+	game := engine.NewGame("Lakra Game Prototype", 320, 224, 2, 64, framePainter, keyListener, levels)
+	game.TickRate = 64
+
+	if err := game.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // framePainter adds additional graphics to the painted level frame
@@ -112,24 +120,19 @@ func keyListener(event key.Event, gameObject *engine.GameObject) {
 
 		if event.Direction == key.DirPress && gameObject.IsResting() == true && gameObject.Direction == engine.DirStationary {
 			gameObject.Direction = engine.DirLeft
-			gameObject.CurrentState = "moving"
 		} else if event.Direction == key.DirRelease && gameObject.Direction == engine.DirLeft {
 			gameObject.Direction = engine.DirStationary
-			gameObject.CurrentState = "standing"
 		}
 	case key.CodeRightArrow:
 
 		if event.Direction == key.DirPress && gameObject.IsResting() == true && gameObject.Direction == engine.DirStationary {
 			gameObject.Direction = engine.DirRight
-			gameObject.CurrentState = "moving"
 		} else if event.Direction == key.DirRelease && gameObject.Direction == engine.DirRight {
 			gameObject.Direction = engine.DirStationary
-			gameObject.CurrentState = "standing"
 		}
 	case key.CodeSpacebar:
 
 		if event.Direction == key.DirPress && gameObject.IsResting() == true {
-			gameObject.CurrentState = "jumping"
 			gameObject.Velocity.Y = 6
 		}
 	}
@@ -143,8 +146,9 @@ func beforePaint(level *engine.Level) {
 
 		if gameObject.IsControllable {
 
-			xOffset := gameObject.Position.X - float64(level.Game.Width/2)
-			yOffset := gameObject.Position.Y - float64(level.Game.Height/2)
+			position := gameObject.RenderPosition()
+			xOffset := position.X - float64(level.Game.Width/2)
+			yOffset := position.Y - float64(level.Game.Height/2)
 
 			if xOffset < 0 {
 				xOffset = 0
@@ -154,7 +158,7 @@ func beforePaint(level *engine.Level) {
 				xOffset = getMaxScrollX()
 			}
 
-			if gameObject.Position.Y < float64(level.Game.Height/2) {
+			if position.Y < float64(level.Game.Height/2) {
 				yOffset = 0
 			}
 
@@ -257,11 +261,44 @@ var spriteCharacterLanding02, _ = engine.CreateSprite(paletteCharacter, []int{0x
 var spriteCharacterLanding12, _ = engine.CreateSprite(paletteCharacter, []int{0xcccc4840, 0x04888888, 0xccc54884, 0x48888888, 0x55574888, 0x88888888, 0xbd094888, 0x88888888, 0xdb334888, 0x88888888, 0xdd3b3488, 0x88888888, 0xddbbb488, 0x88888888, 0xddbbb488, 0x88888888, 0xdb3bb348, 0x88888888, 0x943bbb48, 0x88888888, 0x344bbb48, 0x88888888, 0x3443b348, 0x88888888, 0x48849948, 0x88888888, 0x88842248, 0x88888888, 0x88842224, 0x88888888, 0x88884444, 0x88888888})
 var characterLanding, _ = engine.CreateSpriteGroup(2, 3, &[]*engine.Sprite{spriteCharacterLanding00, spriteCharacterLanding10, spriteCharacterLanding01, spriteCharacterLanding11, spriteCharacterLanding02, spriteCharacterLanding12})
 
+// characterStateMachine picks the character's state from how it is moving, so
+// the key listener only has to set its direction and jump velocity
+var characterStateMachine = &engine.StateMachine{
+	InitialState: "standing",
+	Transitions: []engine.StateTransition{
+		{From: engine.AnyState, To: "jumping", Guard: isRising, IsAutomatic: true},
+		{From: engine.AnyState, To: "landing", Guard: isFalling, IsAutomatic: true},
+		{From: engine.AnyState, To: "moving", Guard: isWalking, IsAutomatic: true},
+		{From: engine.AnyState, To: "standing", Guard: isStanding, IsAutomatic: true},
+	},
+}
+
+// isRising determines whether a game object is on its way up
+func isRising(gameObject *engine.GameObject) bool {
+	return gameObject.Velocity.Y > 0
+}
+
+// isFalling determines whether a game object is dropping towards its floor
+func isFalling(gameObject *engine.GameObject) bool {
+	return gameObject.Velocity.Y < 0 && gameObject.IsResting() == false
+}
+
+// isWalking determines whether a game object is moving along its floor
+func isWalking(gameObject *engine.GameObject) bool {
+	return gameObject.IsResting() == true && gameObject.Direction != engine.DirStationary
+}
+
+// isStanding determines whether a game object is still on its floor
+func isStanding(gameObject *engine.GameObject) bool {
+	return gameObject.IsResting() == true && gameObject.Direction == engine.DirStationary
+}
+
 // getCharacter gets the controllable character object
 func getCharacter(xPos float64, yPos float64) *engine.GameObject {
 
 	return &engine.GameObject{
 		CurrentState: "standing",
+		StateMachine: characterStateMachine,
 		States: engine.GameObjectStates{
 			"standing": engine.SpriteSeries{
 				Sprites:         []engine.SpriteInterface{characterStanding},
@@ -284,6 +321,13 @@ func getCharacter(xPos float64, yPos float64) *engine.GameObject {
 			X: xPos,
 			Y: yPos,
 		},
+		// The top of the character's sprites is transparent, so only the
+		// body takes part in collisions
+		Hitbox: &engine.Hitbox{
+			Offset: engine.Vector{X: 6, Y: 0},
+			Width:  20,
+			Height: 34,
+		},
 		Mass: 0.4,
 		Velocity: engine.Vector{
 			X: 2,
@@ -311,6 +355,7 @@ func characterCollisionHandler(gameObject *engine.GameObject, collision engine.C
 		gameObject.Level.Gravity = 0.3
 		collision.GameObject.IsHidden = true
 		collision.GameObject.IsInteractive = false
+		gameObject.FlashPalette(color.RGBA{255, 255, 255, 255}, 200*time.Millisecond)
 	}
 
 }
@@ -320,26 +365,17 @@ func characterEventHandler(eventCode int, gameObject *engine.GameObject) {
 
 	switch eventCode {
 
-	case engine.EventFreeFall:
-		gameObject.CurrentState = "landing"
-
-	case engine.EventFloorCollision:
-
-		if gameObject.Direction == engine.DirStationary {
-			gameObject.CurrentState = "standing"
-		} else {
-			gameObject.CurrentState = "moving"
-		}
-
 	case engine.EventDropOffLevel:
-		gameObject.Position.X = 20
-		gameObject.Position.Y = 100
-		gameObject.IsFlipped = false
+		gameObject.Level.Game.RestartLevel()
 
 	}
 
 }
 
+// layerFloor is the collision layer floor tiles sit on, so that neighbouring
+// tiles don't report collisions with each other
+const layerFloor = 1 << 1
+
 // Sprite information for the floor
 var paletteFloor = &engine.Palette{"5": color.RGBA{162, 199, 88, 255}, "0": color.RGBA{51, 101, 71, 255}, "1": color.RGBA{24, 44, 59, 255}, "2": color.RGBA{32, 64, 66, 255}, "3": color.RGBA{0, 0, 0, 0}, "4": color.RGBA{87, 153, 69, 255}}
 var spriteFloor, _ = engine.CreateSprite(paletteFloor, []int{0x43343333, 0x43343333, 0x43344334, 0x43344334, 0x55455544, 0x54555544, 0x55555555, 0x55555555, 0x55555555, 0x55555555, 0x55445555, 0x54555445, 0x54555555, 0x45555554, 0x44554455, 0x45540554, 0x04544445, 0x05440050, 0x00440040, 0x00040400, 0x40400000, 0x44004404, 0x44004440, 0x44404404, 0x44044400, 0x04404000, 0x04044000, 0x00400000, 0x11140010, 0x01001000, 0x22210120, 0x12012101})
@@ -369,8 +405,11 @@ func getFloor(xPos float64, yPos float64) *engine.GameObject {
 		IsFlipped:        false,
 		IsControllable:   false,
 		IsFloor:          true,
+		IsSolid:          true,
 		IsInteractive:    true,
 		IsHidden:         false,
+		CollisionLayer:   layerFloor,
+		CollisionMask:    engine.CollisionMaskAll &^ layerFloor,
 		DynamicData:      engine.DynamicData{},
 		FloorY:           0,
 		EventHandler:     func(eventCode int, gameObject *engine.GameObject) {},
@@ -457,6 +496,7 @@ func getPowerup(xPos float64, yPos float64) *engine.GameObject {
 		IsFloor:          false,
 		IsInteractive:    true,
 		IsHidden:         false,
+		IsPixelPerfect:   true,
 		DynamicData:      engine.DynamicData{"type": "powerup"},
 		FloorY:           0,
 		EventHandler:     func(eventCode int, gameObject *engine.GameObject) {},
@@ -1,6 +1,16 @@
 package engine
 
-import "golang.org/x/mobile/event/key"
+import (
+	"context"
+	"errors"
+	"image"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/mobile/event/key"
+)
 
 // Game is a struct that defines a game and the window that contains it
 type Game struct {
@@ -14,11 +24,45 @@ type Game struct {
 	Levels []*Level
 	CurrentLevelID int
 	CurrentFrame int
+	Backend Backend
+	TickRate int
+	lifecycleMutex sync.Mutex
+	cancel context.CancelFunc
+	accumulator time.Duration
+	interpolation float64
+	hasEnteredLevel bool
+	transition *ScreenTransition
 }
 
-// CreateGame sets up a game and its window
+// CreateGame sets up a game and runs it in a window, returning once the
+// window has been closed. Errors opening the window are logged, so use NewGame
+// and Run to handle them instead
 func CreateGame(title string, width int, height int, scaleFactor int, targetFrameRate int, framePainter FramePainter, keyListener KeyListener, levels []*Level) *Game {
 
+	return CreateGameWithBackend(&WindowBackend{}, title, width, height, scaleFactor, targetFrameRate, framePainter, keyListener, levels)
+
+}
+
+// CreateGameWithBackend sets up a game and runs it on the provided backend,
+// returning once the backend has finished. Errors from the backend are logged,
+// so use NewGame and Run to handle them instead
+func CreateGameWithBackend(backend Backend, title string, width int, height int, scaleFactor int, targetFrameRate int, framePainter FramePainter, keyListener KeyListener, levels []*Level) *Game {
+
+	game := NewGame(title, width, height, scaleFactor, targetFrameRate, framePainter, keyListener, levels)
+	game.Backend = backend
+
+	if err := game.Run(context.Background()); err != nil {
+		log.Println("Error running game: " + err.Error())
+	}
+
+	return game
+
+}
+
+// NewGame sets up a game without running it, so that it can be configured
+// further before Run or Step are called
+func NewGame(title string, width int, height int, scaleFactor int, targetFrameRate int, framePainter FramePainter, keyListener KeyListener, levels []*Level) *Game {
+
 	game := Game{
 		Title:           title,
 		Width:           width,
@@ -30,24 +74,228 @@ func CreateGame(title string, width int, height int, scaleFactor int, targetFram
 		Levels:          levels,
 		CurrentLevelID:  0,
 		CurrentFrame:    0,
+		Backend:         &WindowBackend{},
 	}
 
 	for _, level := range levels {
 		level.Game = &game
 	}
 
-	createWindow(&game)
-
 	return &game
 
 }
 
+// Run runs the game on its backend until the backend finishes, the context is
+// cancelled or Stop is called
+func (game *Game) Run(ctx context.Context) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	game.lifecycleMutex.Lock()
+	game.cancel = cancel
+	game.lifecycleMutex.Unlock()
+
+	backend := game.Backend
+
+	if backend == nil {
+		backend = &WindowBackend{}
+	}
+
+	return backend.Run(ctx, game)
+}
+
+// Step advances the game by a single frame at the target frame rate and
+// returns the painted stage
+func (game *Game) Step() *image.RGBA {
+	return game.renderFrame(game.targetFrameDuration(), float64(game.TargetFrameRate))
+}
+
+// Stop asks a running game to finish, causing Run to return. Stopping a game
+// that is not running has no effect
+func (game *Game) Stop() {
+
+	game.lifecycleMutex.Lock()
+	defer game.lifecycleMutex.Unlock()
+
+	if game.cancel != nil {
+		game.cancel()
+	}
+}
 
 // CurrentLevel gets the current level object
 func (game *Game) CurrentLevel() *Level {
 	return game.Levels[game.CurrentLevelID]
 }
 
+// TickDuration gets the length of a single simulation tick. Velocities are
+// applied once per tick, so they are independent of the rate frames are
+// painted at
+func (game *Game) TickDuration() time.Duration {
+
+	tickRate := game.TickRate
+
+	if tickRate <= 0 {
+		tickRate = DefaultTickRate
+	}
+
+	return time.Second / time.Duration(tickRate)
+}
+
+// Interpolation gets how far (between 0 and 1) the frame being painted sits
+// between the previous simulation tick and the next one
+func (game *Game) Interpolation() float64 {
+	return game.interpolation
+}
+
+// targetFrameDuration gets the length of a frame at the target frame rate
+func (game *Game) targetFrameDuration() time.Duration {
+	return time.Second / time.Duration(game.TargetFrameRate)
+}
+
+// tick runs as many fixed simulation ticks as fit into the time that has
+// elapsed since the last frame, carrying the remainder over to the next frame
+func (game *Game) tick(elapsed time.Duration) {
+
+	tickDuration := game.TickDuration()
+	game.accumulator += elapsed
+	ticks := 0
+
+	for game.accumulator >= tickDuration {
+
+		// Drop the backlog rather than spiralling when a machine can't keep up
+		if ticks == MaxTicksPerFrame {
+			game.accumulator %= tickDuration
+			break
+		}
+
+		game.CurrentLevel().Update()
+		game.accumulator -= tickDuration
+		ticks++
+	}
+
+	game.interpolation = float64(game.accumulator) / float64(tickDuration)
+}
+
+// NextLevel moves the game on to the level after the current one
+func (game *Game) NextLevel() error {
+
+	if game.CurrentLevelID+1 >= len(game.Levels) {
+		return errors.New("There is no level after level " + strconv.Itoa(game.CurrentLevelID))
+	}
+
+	return game.GoToLevel(game.CurrentLevelID + 1)
+}
+
+// GoToLevel exits the current level and enters the level with the given ID.
+// Levels that were built from a factory are rebuilt as they are entered so
+// that they always start afresh
+func (game *Game) GoToLevel(levelID int) error {
+
+	if levelID < 0 || levelID >= len(game.Levels) {
+		return errors.New("Level " + strconv.Itoa(levelID) + " does not exist")
+	}
+
+	game.exitCurrentLevel()
+	game.CurrentLevelID = levelID
+
+	if game.CurrentLevel().Factory != nil {
+		game.rebuildCurrentLevel()
+	}
+
+	game.enterCurrentLevel()
+
+	return nil
+}
+
+// RestartLevel rebuilds the current level from its factory and enters it again
+func (game *Game) RestartLevel() error {
+
+	if game.CurrentLevel().Factory == nil {
+		return errors.New("Level " + strconv.Itoa(game.CurrentLevelID) + " has no factory to rebuild it from")
+	}
+
+	game.exitCurrentLevel()
+	game.rebuildCurrentLevel()
+	game.enterCurrentLevel()
+
+	return nil
+}
+
+// rebuildCurrentLevel replaces the current level with a fresh one from its
+// factory
+func (game *Game) rebuildCurrentLevel() {
+
+	factory := game.CurrentLevel().Factory
+	level := factory()
+	level.Factory = factory
+	level.Game = game
+
+	game.Levels[game.CurrentLevelID] = level
+}
+
+// enterCurrentLevel calls the current level's OnEnter hook
+func (game *Game) enterCurrentLevel() {
+
+	game.hasEnteredLevel = true
+	level := game.CurrentLevel()
+
+	if level.OnEnter != nil {
+		level.OnEnter(level)
+	}
+}
+
+// exitCurrentLevel calls the current level's OnExit hook and puts back the
+// original colours of any palettes it was cycling
+func (game *Game) exitCurrentLevel() {
+
+	level := game.CurrentLevel()
+
+	for _, paletteCycle := range level.PaletteCycles {
+		paletteCycle.Reset()
+	}
+
+	if game.hasEnteredLevel == true && level.OnExit != nil {
+		level.OnExit(level)
+	}
+}
+
+// renderFrame runs the simulation for the elapsed time, advances the frame
+// ticker and paints the current level onto a fresh stage, which is shared by
+// every backend
+func (game *Game) renderFrame(elapsed time.Duration, frameRate float64) *image.RGBA {
+
+	// The first level is entered as soon as the game starts
+	if game.hasEnteredLevel == false {
+		game.enterCurrentLevel()
+	}
+
+	game.tick(elapsed)
+
+	game.CurrentFrame++
+
+	if game.CurrentFrame > game.TargetFrameRate {
+		game.CurrentFrame = 1
+	}
+
+	stage := image.NewRGBA(image.Rect(0, 0, game.Width, game.Height))
+	level := game.CurrentLevel()
+
+	if level.BeforePaint != nil {
+		level.BeforePaint(level)
+	}
+
+	level.Repaint(stage)
+
+	if game.FramePainter != nil {
+		game.FramePainter(stage, level, frameRate)
+	}
+
+	game.compositeTransition(stage, elapsed)
+
+	return stage
+}
+
 // BroadCastInput sends the game input to the current level's object if they are controllable
 func (game *Game) BroadcastInput(event key.Event) {
 
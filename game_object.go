@@ -1,23 +1,49 @@
 package engine
 
+import (
+	"math"
+	"time"
+)
+
 // GameObject represented a sprite and its properties
 type GameObject struct {
 	CurrentState string
 	States GameObjectStates
+	StateMachine *StateMachine
 	Position Vector
+	PreviousPosition Vector
 	Mass float64
 	Velocity Vector
 	Direction int
 	IsFlipped bool
+	IsFlippedVertically bool
+	Rotation int
+	Scale int
+	Palette *Palette
+	PaletteRemap map[string]string
+	PaletteFilter PaletteFilter
 	IsControllable bool
 	IsFloor bool
+	IsSolid bool
 	IsInteractive bool
 	IsHidden bool
+	Hitbox *Hitbox
+	IsPixelPerfect bool
+	CollisionLayer uint32
+	CollisionMask uint32
 	Level *Level
 	DynamicData DynamicData
 	FloorY float64
 	EventHandler EventHandler
 	CollisionHandler CollisionHandler
+	AnimationEventHandler AnimationEventHandler
+	machineState string
+	machineStarted bool
+	hasPreviousPosition bool
+	paletteEffect *paletteEffect
+	animationState string
+	animationElapsed time.Duration
+	animationFinished bool
 }
 
 // IsResting determined whether the game object is currently atop another game
@@ -32,56 +58,138 @@ func (gameObject *GameObject) IsResting() bool {
 	return int(gameObject.Position.Y) == int(gameObject.FloorY)
 }
 
+// CollidesWith determines whether the game object and another game object
+// should be tested against each other, which is the case when each one's
+// collision layer is in the other's collision mask. An unset layer falls back
+// to CollisionLayerDefault and an unset mask to CollisionMaskAll
+func (gameObject *GameObject) CollidesWith(otherObject *GameObject) bool {
+
+	return (gameObject.collisionLayer()&otherObject.collisionMask()) != 0 &&
+		(otherObject.collisionLayer()&gameObject.collisionMask()) != 0
+}
+
+// collisionLayer gets the game object's collision layer, falling back to the
+// default layer
+func (gameObject *GameObject) collisionLayer() uint32 {
+
+	if gameObject.CollisionLayer == 0 {
+		return CollisionLayerDefault
+	}
+
+	return gameObject.CollisionLayer
+}
+
+// collisionMask gets the game object's collision mask, falling back to
+// colliding with every layer
+func (gameObject *GameObject) collisionMask() uint32 {
+
+	if gameObject.CollisionMask == 0 {
+		return CollisionMaskAll
+	}
+
+	return gameObject.CollisionMask
+}
+
+// RenderPosition gets the position the game object should be painted at,
+// interpolated between its previous and current simulation positions
+func (gameObject *GameObject) RenderPosition() Vector {
+
+	if gameObject.hasPreviousPosition == false || gameObject.Level == nil || gameObject.Level.Game == nil {
+		return gameObject.Position
+	}
+
+	alpha := gameObject.Level.Game.Interpolation()
+
+	return Vector{
+		X: gameObject.PreviousPosition.X + ((gameObject.Position.X - gameObject.PreviousPosition.X) * alpha),
+		Y: gameObject.PreviousPosition.Y + ((gameObject.Position.Y - gameObject.PreviousPosition.Y) * alpha),
+	}
+}
+
 // CurrentSprite gets the current sprite for the object's state
 func (gameObject *GameObject) CurrentSprite() SpriteInterface {
 
-	spriteSeries := gameObject.States[gameObject.CurrentState]
+	spriteSeries := gameObject.currentSeries()
 	sprite := gameObject.getCurrentSpriteFrame(spriteSeries)
 
 	return sprite
 }
 
-// getCurrentSpriteFrame gets the appropriate frame of a sprite series based on the
-// game's frame ticker
+// getCurrentSpriteFrame gets the appropriate frame of a sprite series based on
+// how long the game object's animation has been playing
 func (gameObject *GameObject) getCurrentSpriteFrame(spriteSeries SpriteSeries) SpriteInterface {
+	return spriteSeries.Sprites[gameObject.AnimationFrame()]
+}
 
-	// if we dont have a level
-	if gameObject.Level != nil {
+// CurrentHitbox gets the hitbox for the game object's current state, falling
+// back to the game object's own hitbox and then to the bounds of its sprite.
+// Hitboxes are flipped, rotated and scaled along with the sprite
+func (gameObject *GameObject) CurrentHitbox() Hitbox {
 
-		game := gameObject.Level.Game
-		framePerSprite :=  (game.TargetFrameRate / spriteSeries.CyclesPerSecond) / len(spriteSeries.Sprites)
-		spriteCounter := 0
-		i := 0
+	sprite := gameObject.CurrentSprite()
+	width := float64(sprite.Width())
+	height := float64(sprite.Height())
+	hitbox := gameObject.currentSeries().Hitbox
 
-		for j := 0; j < game.TargetFrameRate; j++ {
+	if hitbox == nil {
+		hitbox = gameObject.Hitbox
+	}
 
-			i++
+	if hitbox == nil {
+		hitbox = &Hitbox{Width: width, Height: height}
+	}
 
-			if i == framePerSprite {
-				i = 0
-				spriteCounter++
-			}
+	transform := DrawOptions{
+		FlipHorizontal: gameObject.IsFlipped,
+		FlipVertical:   gameObject.IsFlippedVertically,
+		Rotation:       gameObject.Rotation,
+		Scale:          gameObject.Scale,
+	}.transform()
 
-			if spriteCounter >= len(spriteSeries.Sprites) {
-				spriteCounter = 0
-			}
+	return transform.hitbox(*hitbox, width, height)
+}
 
-			if j == game.CurrentFrame {
-				return spriteSeries.Sprites[spriteCounter]
-			}
-		}
+// DrawOptions gets the options the game object's sprite is drawn with
+func (gameObject *GameObject) DrawOptions() DrawOptions {
+
+	return DrawOptions{
+		FlipHorizontal: gameObject.IsFlipped,
+		FlipVertical:   gameObject.IsFlippedVertically,
+		Rotation:       gameObject.Rotation,
+		Scale:          gameObject.Scale,
+		Palette:        gameObject.Palette,
+		PaletteRemap:   gameObject.PaletteRemap,
+		PaletteFilter:  gameObject.paletteFilter(),
 	}
-	return spriteSeries.Sprites[0]
 }
 
-// Width gets width of the game object
+// Width gets width of the game object as drawn, after rotation and scaling
 func (gameObject *GameObject) Width() int {
-	return gameObject.CurrentSprite().Width()
+
+	width, _ := gameObject.drawnSize()
+
+	return width
 }
 
-// Height gets height of the game object
+// Height gets height of the game object as drawn, after rotation and scaling
 func (gameObject *GameObject) Height() int {
-	return gameObject.CurrentSprite().Height()
+
+	_, height := gameObject.drawnSize()
+
+	return height
+}
+
+// drawnSize gets the size of the game object's current sprite once rotated
+// and scaled
+func (gameObject *GameObject) drawnSize() (int, int) {
+
+	sprite := gameObject.CurrentSprite()
+	options := DrawOptions{
+		Rotation: gameObject.Rotation,
+		Scale:    gameObject.Scale,
+	}
+
+	return options.Size(sprite.Width(), sprite.Height())
 }
 
 // RecalculatePosition recalculates the latest X and Y position of the game
@@ -179,10 +287,12 @@ func (gameObject *GameObject) GetCollisionEdge(collidingObject *GameObject) stri
 
 	// where is the game object's outer edge in relation to the colliding
 	// object?
-	isLeft := gameObject.Position.X < collidingObject.Position.X
-	isRight := (gameObject.Position.X + float64(gameObject.Width())) > (collidingObject.Position.X + float64(collidingObject.Width()))
-	isBottom := gameObject.Position.Y < collidingObject.Position.Y
-	isTop := (gameObject.Position.Y +float64(gameObject.Height())) > (collidingObject.Position.Y + float64(collidingObject.Height()))
+	box := gameObject.bounds()
+	collidingBox := collidingObject.bounds()
+	isLeft := box.X < collidingBox.X
+	isRight := (box.X + box.Width) > (collidingBox.X + collidingBox.Width)
+	isBottom := box.Y < collidingBox.Y
+	isTop := (box.Y + box.Height) > (collidingBox.Y + collidingBox.Height)
 
 	// If both objects are at the rest a simple 'left' or 'right' can be assumed
 	// regardless of the height of either object
@@ -238,3 +348,69 @@ func (gameObject *GameObject) GetCollisionEdge(collidingObject *GameObject) stri
 	// colliding object
 	return EdgeNone
 }
+
+// overlap gets how far the game object penetrates another game object along
+// each axis, with values of zero or less meaning they don't intersect
+func (gameObject *GameObject) overlap(otherObject *GameObject) (float64, float64) {
+
+	box := gameObject.bounds()
+	otherBox := otherObject.bounds()
+	overlapX := math.Min(box.X+box.Width, otherBox.X+otherBox.Width) - math.Max(box.X, otherBox.X)
+	overlapY := math.Min(box.Y+box.Height, otherBox.Y+otherBox.Height) - math.Max(box.Y, otherBox.Y)
+
+	return overlapX, overlapY
+}
+
+// pushOutOf moves the game object out of a solid object through the given
+// edge, stopping any movement that would carry it back in. Horizontal speed is
+// kept in Velocity.X and applied in the object's Direction, so walls stop
+// objects walking into them by making them stationary
+func (gameObject *GameObject) pushOutOf(solidObject *GameObject, edge string) {
+
+	hitbox := gameObject.CurrentHitbox()
+	solidBox := solidObject.bounds()
+
+	switch edge {
+	case EdgeLeft:
+
+		gameObject.Position.X = solidBox.X - hitbox.Offset.X - hitbox.Width
+
+		if gameObject.Direction == DirRight {
+			gameObject.Direction = DirStationary
+		}
+
+		gameObject.EventHandler(EventWallCollision, gameObject)
+
+	case EdgeRight:
+
+		gameObject.Position.X = solidBox.X + solidBox.Width - hitbox.Offset.X
+
+		if gameObject.Direction == DirLeft {
+			gameObject.Direction = DirStationary
+		}
+
+		gameObject.EventHandler(EventWallCollision, gameObject)
+
+	case EdgeBottom:
+
+		gameObject.Position.Y = solidBox.Y - hitbox.Offset.Y - hitbox.Height
+
+		if gameObject.Velocity.Y > 0 {
+			gameObject.Velocity.Y = 0
+		}
+
+		gameObject.EventHandler(EventCeilingCollision, gameObject)
+
+	case EdgeTop:
+
+		wasFalling := gameObject.Velocity.Y < 0
+
+		gameObject.Position.Y = solidBox.Y + solidBox.Height - hitbox.Offset.Y
+		gameObject.FloorY = gameObject.Position.Y
+
+		if wasFalling == true {
+			gameObject.Velocity.Y = 0
+			gameObject.EventHandler(EventFloorCollision, gameObject)
+		}
+	}
+}
@@ -1,165 +1,440 @@
 package engine
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
 	"image"
 	"image/color"
 	_ "image/png"
 	"io/ioutil"
-	"log"
-	"math"
 	"os"
 	"strconv"
 	"strings"
 )
 
+// maxReportedPixels is the number of pixels listed when an image has colours
+// missing from the palette it is generated with
+const maxReportedPixels = 10
+
+// GeneratorOptions is a struct that defines how generated files refer to the
+// engine, which palette they use and how the image is cut up. The engine is
+// imported from DefaultEngineImportPath as "engine" unless told otherwise, and
+// images without a palette get one built from their own colours. Images are
+// cut into 16x16 sprites unless given another tile size, and are a single
+// sprite group unless given a frame size, in which case each frame becomes a
+// sprite group of its own. Given states, each row of frames is the animation
+// for a state and the image is exported as GameObjectStates. Images with too
+// many colours can be quantised down to 16 (one of which is transparent), or
+// to the colours of the palette when one is given, and OnQuantise is told how
+// much the image changed
+type GeneratorOptions struct {
+	ImportPath  string
+	ImportAlias string
+	Palette     *Palette
+	TileWidth   int
+	TileHeight  int
+	FrameWidth  int
+	FrameHeight int
+	States      []GeneratedState
+	Quantise    bool
+	Dither      bool
+	OnQuantise  QuantisationHandler
+}
+
+// GeneratedState is a struct that defines the game object state animated by a
+// row of a sprite sheet, with the states given to the generator taking the
+// sheet's rows in order. Rows may have fewer frames than the sheet has
+// columns, and use every column unless told otherwise
+type GeneratedState struct {
+	Name            string `json:"name"`
+	CyclesPerSecond int    `json:"cyclesPerSecond"`
+	Frames          int    `json:"frames"`
+}
+
 // GenerateFromPNGFile generates a SpriteGroup package file from an image on disk
-func GenerateFromPNGFile(inputFile string, outputFile string, packageName string, exportedSpriteName string, palettes ...Palette) {
-	imgFile, imgFileErr := os.Open(inputFile)
-	imgFileConfig, imgFileConfErr := os.Open(inputFile)
+func GenerateFromPNGFile(inputFile string, outputFile string, packageName string, exportedSpriteName string, palettes ...Palette) error {
+	return GenerateFromPNGFileWithOptions(inputFile, outputFile, packageName, exportedSpriteName, optionsFromPalettes(palettes))
+}
+
+// GenerateFromPNGFileWithOptions generates a SpriteGroup package file from an
+// image on disk using the given generator options
+func GenerateFromPNGFileWithOptions(inputFile string, outputFile string, packageName string, exportedSpriteName string, options GeneratorOptions) error {
 
-	if imgFileErr != nil || imgFileConfErr != nil {
-		log.Fatal("Error reading input file")
+	imgFile, err := os.Open(inputFile)
+
+	if err != nil {
+		return errors.New("Error reading input file: " + err.Error())
 	}
 
 	defer imgFile.Close()
-	defer imgFileConfig.Close()
 
-	img, _, imgErr := image.Decode(imgFile)
-	config, _, confErr := image.DecodeConfig(imgFileConfig)
+	img, _, err := image.Decode(imgFile)
 
-	if imgErr != nil || confErr != nil {
-		log.Fatal("Error reading image file")
+	if err != nil {
+		return errors.New("Error reading image file: " + err.Error())
+	}
+
+	source, err := GenerateFromImageWithOptions(img, packageName, exportedSpriteName, options)
+
+	if err != nil {
+		return err
 	}
 
-	if config.Width%16 != 0 || config.Height%16 != 0 {
-		log.Fatal("The image width and/or height was not a multiple of 16")
+	// Write the output file to disk
+	if err := ioutil.WriteFile(outputFile, source, 0644); err != nil {
+		return errors.New("Error writing to output file: " + err.Error())
 	}
 
-	palette := Palette{}
+	return nil
+}
+
+// GenerateFromImage generates the gofmt'd source of a SpriteGroup package file
+// from an image made up of 16x16 sprites. Without a palette, slots are given
+// to the image's colours in the order they first appear (reading left to right
+// and then top to bottom), so unchanged images always generate the same source
+func GenerateFromImage(img image.Image, packageName string, exportedSpriteName string, palettes ...Palette) ([]byte, error) {
+	return GenerateFromImageWithOptions(img, packageName, exportedSpriteName, optionsFromPalettes(palettes))
+}
+
+// GenerateFromImageWithOptions generates the gofmt'd source of a SpriteGroup
+// package file from an image using the given generator options. Every pixel
+// of the image must have a colour from the options' palette when one is given
+func GenerateFromImageWithOptions(img image.Image, packageName string, exportedSpriteName string, options GeneratorOptions) ([]byte, error) {
+
+	bounds := img.Bounds()
+	tileWidth, tileHeight := options.tileSize()
+	frameWidth, frameHeight := options.frameSize(bounds)
+
+	if tileWidth <= 0 || tileHeight <= 0 || frameWidth <= 0 || frameHeight <= 0 {
+		return nil, errors.New("Tile and frame sizes must be greater than 0")
+	}
+
+	if bounds.Dx()%frameWidth != 0 || bounds.Dy()%frameHeight != 0 {
+		return nil, errors.New("The image width and/or height was not a multiple of the " + strconv.Itoa(frameWidth) + "x" + strconv.Itoa(frameHeight) + " frame size")
+	}
+
+	if frameWidth%tileWidth != 0 || frameHeight%tileHeight != 0 {
+
+		// Images that aren't cut into frames are a single frame
+		if frameWidth == bounds.Dx() && frameHeight == bounds.Dy() {
+			return nil, errors.New("The image width and/or height was not a multiple of the " + strconv.Itoa(tileWidth) + "x" + strconv.Itoa(tileHeight) + " tile size")
+		}
+
+		return nil, errors.New("The frame size of " + strconv.Itoa(frameWidth) + "x" + strconv.Itoa(frameHeight) + " was not a multiple of the " + strconv.Itoa(tileWidth) + "x" + strconv.Itoa(tileHeight) + " tile size")
+	}
+
+	if options.Quantise == true {
+
+		var report QuantisationReport
+
+		if options.Palette != nil {
+			img, report = quantiseToPalette(img, *options.Palette, options.Dither)
+		} else {
+			img, report = QuantiseImage(img, len(paletteSlots), options.Dither)
+		}
+
+		if options.OnQuantise != nil {
+			options.OnQuantise(report)
+		}
+	}
+
+	var palette *Palette
+	var slots map[color.RGBA]string
 
 	// If a pallete has been provided use that
-	if len(palettes) > 0 {
+	if options.Palette != nil {
+
+		palette = options.Palette
+		slots = paletteSlotsByColour(*options.Palette)
 
-		palette = palettes[0]
+		if err := checkPaletteCoverage(img, slots); err != nil {
+			return nil, err
+		}
 
 		// Otherwise, generate one based on the image
 	} else {
 
-		tempPalette := Palette{}
+		var err error
 
-		for x := 0; x < config.Width; x++ {
+		if palette, slots, err = createPaletteFromImage(img); err != nil {
+			return nil, err
+		}
+	}
 
-			for y := 0; y < config.Height; y++ {
+	importPath := options.ImportPath
+	engineName := options.ImportAlias
 
-				colourString, colourRGBA := getColourStringAndRGBA(img.At(x, y))
-				tempPalette[colourString] = colourRGBA
+	if importPath == "" {
+		importPath = DefaultEngineImportPath
+	}
 
-			}
+	if engineName == "" {
+		engineName = "engine"
+	}
+
+	// Build up the output file
+	writer := &sourceWriter{
+		source:      &bytes.Buffer{},
+		img:         img,
+		slots:       slots,
+		engineName:  engineName,
+		paletteName: "palette_" + exportedSpriteName,
+		tileWidth:   tileWidth,
+		tileHeight:  tileHeight,
+	}
+	source := writer.source
+
+	fmt.Fprintf(source, "package %s\n\n", packageName)
+	fmt.Fprintf(source, "import (\n\"image/color\"\n\n%s %q\n)\n\n", engineName, importPath)
+
+	// Palette
+	fmt.Fprintf(source, "var %s = &%s.Palette{\n", writer.paletteName, engineName)
+
+	for _, paletteSlot := range paletteSlots {
+
+		if colour, ok := (*palette)[paletteSlot]; ok {
+			fmt.Fprintf(source, "%q: color.RGBA{%d, %d, %d, %d},\n", paletteSlot, colour.R, colour.G, colour.B, colour.A)
 		}
+	}
+
+	fmt.Fprintf(source, "}\n\n")
+
+	// A single frame is exported as the sprite group itself
+	columns := bounds.Dx() / frameWidth
+	rows := bounds.Dy() / frameHeight
 
-		if len(tempPalette) > 16 {
-			log.Fatal("More than 16 colours used in image palette")
+	if len(options.States) > 0 {
+
+		if err := writer.writeStates(exportedSpriteName, options.States, bounds, frameWidth, frameHeight); err != nil {
+			return nil, err
 		}
 
-		paletteSlots := []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "a", "b", "c", "d", "e", "f"}
-		slotCount := 0
+	} else if columns == 1 && rows == 1 {
+		writer.writeSpriteGroup(exportedSpriteName, "sprite_"+exportedSpriteName, bounds)
+	} else {
+
+		frameNames := []string{}
+
+		for row := 0; row < rows; row++ {
 
-		for _, colour := range tempPalette {
-			palette[paletteSlots[slotCount]] = colour
-			slotCount++
+			for column := 0; column < columns; column++ {
+
+				frame := len(frameNames)
+				frameName := fmt.Sprintf("frame_%s_%d", exportedSpriteName, frame)
+				frameArea := image.Rect(0, 0, frameWidth, frameHeight).Add(bounds.Min).Add(image.Pt(column*frameWidth, row*frameHeight))
+
+				writer.writeSpriteGroup(frameName, fmt.Sprintf("sprite_%s_%d", exportedSpriteName, frame), frameArea)
+				frameNames = append(frameNames, frameName)
+			}
 		}
+
+		// Frames are exported left to right and then top to bottom
+		fmt.Fprintf(source, "\nvar %s = []%s.SpriteInterface{%s}\n", exportedSpriteName, engineName, strings.Join(frameNames, ", "))
+	}
+
+	formatted, err := format.Source(source.Bytes())
+
+	if err != nil {
+		return nil, errors.New("Error formatting generated source: " + err.Error())
+	}
+
+	return formatted, nil
+}
+
+// tileSize gets the size of the sprites images are cut into
+func (options GeneratorOptions) tileSize() (int, int) {
+
+	if options.TileWidth == 0 && options.TileHeight == 0 {
+		return 16, 16
 	}
 
-	// Get palette slot for each pixel
-	sprites := map[string][]string{}
+	return options.TileWidth, options.TileHeight
+}
+
+// frameSize gets the size of the frames an image is cut into, which is the
+// whole image if no frame size is set
+func (options GeneratorOptions) frameSize(bounds image.Rectangle) (int, int) {
 
-	for y := 0; y < config.Height; y++ {
+	if options.FrameWidth == 0 && options.FrameHeight == 0 {
+		return bounds.Dx(), bounds.Dy()
+	}
 
-		spriteCountY := int(math.Floor(float64(y) / 16))
+	return options.FrameWidth, options.FrameHeight
+}
 
-		for x := 0; x < config.Width; x++ {
+// sourceWriter is a struct that holds what is needed to write the sprites of
+// an image out as Go source
+type sourceWriter struct {
+	source      *bytes.Buffer
+	img         image.Image
+	slots       map[color.RGBA]string
+	engineName  string
+	paletteName string
+	tileWidth   int
+	tileHeight  int
+}
 
-			spriteCountX := int(math.Floor(float64(x) / 16))
-			spriteCoord := strconv.Itoa(spriteCountX) + "," + strconv.Itoa(spriteCountY)
-			_, colourRGBA := getColourStringAndRGBA(img.At(x, y))
+// writeSpriteGroup writes a sprite for every tile in an area of the image,
+// named after their column and row, followed by a sprite group of them
+func (writer *sourceWriter) writeSpriteGroup(groupName string, spritePrefix string, area image.Rectangle) {
 
-			for paletteSlot, paletteColour := range palette {
+	columns := area.Dx() / writer.tileWidth
+	rows := area.Dy() / writer.tileHeight
+	spriteNames := []string{}
 
-				if paletteColour == colourRGBA {
-					sprites[spriteCoord] = append(sprites[spriteCoord], paletteSlot)
-				}
+	for y := 0; y < rows; y++ {
 
+		for x := 0; x < columns; x++ {
+
+			spriteName := fmt.Sprintf("%s_%d_%d", spritePrefix, x, y)
+			spriteArea := image.Rect(0, 0, writer.tileWidth, writer.tileHeight).Add(area.Min).Add(image.Pt(x*writer.tileWidth, y*writer.tileHeight))
+			scanlines := []string{}
+
+			for _, scanline := range encodeScanlines(writer.img, writer.slots, spriteArea) {
+				scanlines = append(scanlines, fmt.Sprintf("0x%08x", scanline))
+			}
+
+			// 16x16 sprites use the original constructor so existing output
+			// doesn't change
+			if writer.tileWidth == 16 && writer.tileHeight == 16 {
+				fmt.Fprintf(writer.source, "var %s, _ = %s.CreateSprite(%s, []int{%s})\n", spriteName, writer.engineName, writer.paletteName, strings.Join(scanlines, ", "))
+			} else {
+				fmt.Fprintf(writer.source, "var %s, _ = %s.CreateSpriteWithSize(%s, %d, %d, []int{%s})\n", spriteName, writer.engineName, writer.paletteName, writer.tileWidth, writer.tileHeight, strings.Join(scanlines, ", "))
 			}
 
+			spriteNames = append(spriteNames, spriteName)
 		}
+	}
+
+	fmt.Fprintf(writer.source, "\nvar %s, _ = %s.CreateSpriteGroup(%d, %d, &[]*%s.Sprite{%s})\n\n", groupName, writer.engineName, columns, rows, writer.engineName, strings.Join(spriteNames, ", "))
+}
 
+// writeStates writes a sprite group for every frame of each state's row of the
+// image, followed by GameObjectStates with a sprite series for each state
+func (writer *sourceWriter) writeStates(exportedSpriteName string, states []GeneratedState, bounds image.Rectangle, frameWidth int, frameHeight int) error {
+
+	columns := bounds.Dx() / frameWidth
+	rows := bounds.Dy() / frameHeight
+
+	if len(states) > rows {
+		return errors.New(strconv.Itoa(len(states)) + " states were given, but the image only has " + strconv.Itoa(rows) + " rows of frames")
 	}
 
-	// Build up the output file
-	spriteStrings := []string{}
-	spriteNames := []string{}
+	stateNames := map[string]bool{}
+	series := []string{}
 
-	// Palette
-	paletteName := "palette_" + exportedSpriteName
-	paletteString := "var " + paletteName + " = &engine.Palette{"
+	for row, state := range states {
+
+		frames := state.Frames
 
-	for paletteSlot, colour := range palette {
-		paletteString += `"` + paletteSlot + `": color.RGBA{` + strconv.Itoa(int(colour.R)) + `,` + strconv.Itoa(int(colour.G)) + `,` + strconv.Itoa(int(colour.B)) + `,` + strconv.Itoa(int(colour.A)) + `},`
+		if frames == 0 {
+			frames = columns
+		}
+
+		if state.Name == "" || stateNames[state.Name] == true {
+			return errors.New("State " + strconv.Itoa(row) + " needs a name that no other state has")
+		}
+
+		if frames < 0 || frames > columns {
+			return errors.New("State " + strconv.Quote(state.Name) + " has " + strconv.Itoa(frames) + " frames, but the image only has " + strconv.Itoa(columns) + " columns of frames")
+		}
+
+		stateNames[state.Name] = true
+		frameNames := []string{}
+
+		for column := 0; column < frames; column++ {
+
+			frame := (row * columns) + column
+			frameName := fmt.Sprintf("frame_%s_%d", exportedSpriteName, frame)
+			frameArea := image.Rect(0, 0, frameWidth, frameHeight).Add(bounds.Min).Add(image.Pt(column*frameWidth, row*frameHeight))
+
+			writer.writeSpriteGroup(frameName, fmt.Sprintf("sprite_%s_%d", exportedSpriteName, frame), frameArea)
+			frameNames = append(frameNames, frameName)
+		}
+
+		series = append(series, fmt.Sprintf("%q: %s.SpriteSeries{\nSprites: []%s.SpriteInterface{%s},\nCyclesPerSecond: %d,\n},\n", state.Name, writer.engineName, writer.engineName, strings.Join(frameNames, ", "), state.CyclesPerSecond))
 	}
 
-	paletteString += "}"
+	fmt.Fprintf(writer.source, "\nvar %s = %s.GameObjectStates{\n%s}\n", exportedSpriteName, writer.engineName, strings.Join(series, ""))
 
-	// Sprites
-	for y := 0; y < (config.Height / 16); y++ {
+	return nil
+}
 
-		for x := 0; x < (config.Width / 16); x++ {
+// optionsFromPalettes gets the generator options for the optional palette the
+// original generator functions accept
+func optionsFromPalettes(palettes []Palette) GeneratorOptions {
 
-			spriteName := "sprite_" + exportedSpriteName + "_" + strconv.Itoa(x) + "_" + strconv.Itoa(y)
-			spriteString := "var " + spriteName + ", _ = engine.CreateSprite(" + paletteName + ", []int{"
-			spriteNames = append(spriteNames, spriteName)
+	if len(palettes) == 0 {
+		return GeneratorOptions{}
+	}
+
+	return GeneratorOptions{Palette: &palettes[0]}
+}
 
-			for i, paletteSlot := range sprites[strconv.Itoa(x)+","+strconv.Itoa(y)] {
+// checkPaletteCoverage makes sure that every pixel of an image has a colour
+// from a palette, listing the coordinates of the first pixels that don't
+func checkPaletteCoverage(img image.Image, slots map[color.RGBA]string) error {
 
-				if (i % 8) == 0 {
-					spriteString += "0x"
-				}
+	bounds := img.Bounds()
+	missingPixels := []string{}
+	missingCount := 0
 
-				spriteString += paletteSlot
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 
-				if (i % 8) == 7 {
-					spriteString += ","
-				}
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+
+			colour := imageColourAt(img, x, y)
+
+			if _, ok := slots[colour]; ok {
+				continue
 			}
 
-			spriteString += "}}"
-			spriteStrings = append(spriteStrings, spriteString)
+			missingCount++
 
+			// Colours are listed unpremultiplied, as image editors show them
+			if len(missingPixels) < maxReportedPixels {
+				shownColour := color.NRGBAModel.Convert(colour).(color.NRGBA)
+				missingPixels = append(missingPixels, fmt.Sprintf("(%d,%d) #%02x%02x%02x%02x", x, y, shownColour.R, shownColour.G, shownColour.B, shownColour.A))
+			}
 		}
 	}
 
-	fileContents := `package ` + packageName + `
-	import (
-		"image/color"
-		"github.com/tesh254/lakra/engine"
-	)
-	` + paletteString + `
-	` + strings.Join(spriteStrings, "\n") +`
-	var ` + exportedSpriteName + `, _ = engine.CreateSpriteGroup(` + strconv.Itoa(config.Width/16) + `, ` + strconv.Itoa(config.Height/16) + `, &[]*engine.Sprite{` + strings.Join(spriteNames, ",") + `}}`
+	if missingCount == 0 {
+		return nil
+	}
 
-	// Write the output file to disk
-	err := ioutil.WriteFile(outputFile, []byte(fileContents), 0644)
+	message := strconv.Itoa(missingCount) + " pixels have colours that aren't in the palette: " + strings.Join(missingPixels, ", ")
 
-	if err != nil {
-		log.Fatal("Error writing to output file")
+	if missingCount > len(missingPixels) {
+		message += " and " + strconv.Itoa(missingCount-len(missingPixels)) + " more"
 	}
+
+	return errors.New(message)
 }
 
-// getColourStringAndRGBA converts a color.Color object to its string and color.RGBA representations
-func getColourStringAndRGBA(colour color.Color) (string, color.RGBA) {
+// paletteSlotsByColour maps each colour of a palette to its slot, using the
+// lowest slot for colours that appear more than once
+func paletteSlotsByColour(palette Palette) map[color.RGBA]string {
+
+	slots := map[color.RGBA]string{}
 
-	r, g, b, a := colour.RGBA()
-	colourString := strconv.Itoa(int(r)) + "," + strconv.Itoa(int(g)) + "," + strconv.Itoa(int(b)) + "," + strconv.Itoa(int(a))
-	colourRGBA := color.RGBA{uint8(r), uint8(g), uint8(b), uint8(a)}
+	for i := len(paletteSlots) - 1; i >= 0; i-- {
+
+		colour, ok := palette[paletteSlots[i]]
+
+		if ok == false {
+			continue
+		}
+
+		// Every fully transparent colour is read from images as the same colour
+		if colour.A == 0 {
+			colour = color.RGBA{}
+		}
+
+		slots[colour] = paletteSlots[i]
+	}
 
-	return colourString, colourRGBA
+	return slots
 }
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSheet creates a 48x32 image of 3 columns and 2 rows of 16x16 frames,
+// with a transparent border around each frame
+func testSheet() *image.RGBA {
+
+	img := image.NewRGBA(image.Rect(0, 0, 48, 32))
+
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 48; x++ {
+			if x%16 != 0 && y%16 != 0 {
+				img.SetRGBA(x, y, color.RGBA{uint8((x / 16) * 100), uint8((y / 16) * 100), 50, 255})
+			}
+		}
+	}
+
+	return img
+}
+
+// engineShim is a package that re-exports what generated files use from the
+// engine, so that a custom import path can be compiled against
+const engineShim = `package shim
+
+import engine "github.com/tesh254/lakra"
+
+type Palette = engine.Palette
+type Sprite = engine.Sprite
+type SpriteInterface = engine.SpriteInterface
+type SpriteSeries = engine.SpriteSeries
+type GameObjectStates = engine.GameObjectStates
+
+var CreateSprite = engine.CreateSprite
+var CreateSpriteWithSize = engine.CreateSpriteWithSize
+var CreateSpriteGroup = engine.CreateSpriteGroup
+`
+
+func TestGeneratedSourceCompiles(t *testing.T) {
+
+	goBinary, err := exec.LookPath("go")
+
+	if err != nil {
+		t.Skip("go is needed to compile generated source")
+	}
+
+	repoRoot, err := os.Getwd()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		packageName string
+		name        string
+		options     GeneratorOptions
+	}{
+		{"plain", "Plain", GeneratorOptions{}},
+		{"aliased", "Aliased", GeneratorOptions{ImportPath: "generated/shim", ImportAlias: "lakra"}},
+		{"tiles", "Tiles", GeneratorOptions{TileWidth: 8, TileHeight: 16}},
+		{"frames", "Frames", GeneratorOptions{TileWidth: 8, TileHeight: 8, FrameWidth: 16, FrameHeight: 16}},
+		{"states", "States", GeneratorOptions{FrameWidth: 16, FrameHeight: 16, States: []GeneratedState{
+			{Name: "standing", CyclesPerSecond: 1, Frames: 1},
+			{Name: "moving", CyclesPerSecond: 2},
+		}}},
+	}
+
+	moduleDirectory := t.TempDir()
+	goMod := "module generated\n\ngo 1.16\n\nrequire github.com/tesh254/lakra v0.0.0\n\nreplace github.com/tesh254/lakra => " + repoRoot + "\n"
+
+	writeFile(t, filepath.Join(moduleDirectory, "go.mod"), goMod)
+	writeFile(t, filepath.Join(moduleDirectory, "shim", "shim.go"), engineShim)
+
+	if goSum, err := ioutil.ReadFile(filepath.Join(repoRoot, "go.sum")); err == nil {
+		writeFile(t, filepath.Join(moduleDirectory, "go.sum"), string(goSum))
+	}
+
+	for _, testCase := range cases {
+
+		source, err := GenerateFromImageWithOptions(testSheet(), testCase.packageName, testCase.name, testCase.options)
+
+		if err != nil {
+			t.Fatalf("%s: %v", testCase.packageName, err)
+		}
+
+		writeFile(t, filepath.Join(moduleDirectory, testCase.packageName, testCase.packageName+".go"), string(source))
+	}
+
+	command := exec.Command(goBinary, "vet", "./...")
+	command.Dir = moduleDirectory
+	command.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("generated source doesn't compile: %v\n%s", err, output)
+	}
+}
+
+func TestCheckPaletteCoverageListsPixels(t *testing.T) {
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+
+	// 15 pixels down the first column aren't in the palette
+	for y := 1; y < 16; y++ {
+		img.SetRGBA(0, y, color.RGBA{255, 0, 255, 255})
+	}
+
+	palette := Palette{"0": color.RGBA{0, 0, 0, 0}}
+	err := checkPaletteCoverage(img, paletteSlotsByColour(palette))
+
+	if err == nil {
+		t.Fatal("expected pixels missing from the palette to be reported")
+	}
+
+	for _, expected := range []string{"15 pixels", "(0,1) #ff00ffff", "(0,10) #ff00ffff", "and 5 more"} {
+		if strings.Contains(err.Error(), expected) == false {
+			t.Errorf("expected %q in %q", expected, err.Error())
+		}
+	}
+
+	if strings.Contains(err.Error(), "(0,11)") == true {
+		t.Errorf("expected only %d pixels to be listed in %q", maxReportedPixels, err.Error())
+	}
+
+	if _, err := GenerateFromImage(img, "assets", "Missing", palette); err == nil {
+		t.Error("expected the generator to reject pixels missing from the palette")
+	}
+}
+
+// writeFile writes a file, creating its directory first
+func writeFile(t *testing.T, path string, contents string) {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/tesh254/lakra
 
-go 1.13
+go 1.16
 
 require (
 	github.com/D-L-M/spritengine v0.0.0-20180426183433-3294fc8cef56
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"image"
+)
+
+// HeadlessBackend is a struct that defines a backend that runs a game without
+// a window, painting a fixed number of frames as quickly as possible (or
+// painting until the game is stopped when RunUntilStopped is set). This makes
+// it possible to run levels on machines without a display
+type HeadlessBackend struct {
+	Frames          int
+	RunUntilStopped bool
+	Stage           *image.RGBA
+	FrameHandler    func(stage *image.RGBA, frameNumber int)
+}
+
+// Run paints the configured number of frames, keeping the most recently
+// painted stage. Each frame is treated as lasting exactly one frame at the
+// game's target frame rate, so runs are deterministic
+func (backend *HeadlessBackend) Run(ctx context.Context, game *Game) error {
+
+	for i := 0; backend.RunUntilStopped == true || i < backend.Frames; i++ {
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		backend.Stage = game.renderFrame(game.targetFrameDuration(), float64(game.TargetFrameRate))
+
+		if backend.FrameHandler != nil {
+			backend.FrameHandler(backend.Stage, i)
+		}
+	}
+
+	return nil
+}
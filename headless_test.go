@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// headlessGame builds a 64x64 game whose level has a floor tile in the bottom
+// left corner and an object falling towards it from above
+func headlessGame(t *testing.T) (*Game, *GameObject) {
+
+	floor := testObject(testSprite(t), 0, 0)
+	floor.IsFloor = true
+
+	// The faller hangs half over the floor tile, so lands on it
+	faller := testObject(testSprite(t), 8, 40)
+	faller.Mass = 1
+
+	level := &Level{
+		BackgroundColour: color.RGBA{126, 192, 238, 255},
+		Gravity:          1,
+		GameObjects:      []*GameObject{floor, faller},
+	}
+
+	game := NewGame("Headless", 64, 64, 1, 60, func(stage *image.RGBA, level *Level, frameRate float64) {}, nil, []*Level{level})
+
+	return game, faller
+}
+
+func TestHeadlessBackendRunsLevel(t *testing.T) {
+
+	game, faller := headlessGame(t)
+	paintedFrames := 0
+
+	backend := &HeadlessBackend{
+		Frames: 30,
+		FrameHandler: func(stage *image.RGBA, frameNumber int) {
+			paintedFrames++
+		},
+	}
+
+	game.Backend = backend
+
+	if err := game.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if paintedFrames != 30 || game.CurrentFrame != 30 {
+		t.Fatalf("expected 30 frames, painted %d with CurrentFrame %d", paintedFrames, game.CurrentFrame)
+	}
+
+	// The faller should have landed on top of the floor tile
+	if faller.Position.Y != 16 || faller.IsResting() == false {
+		t.Fatalf("expected the faller to rest at Y 16, it is at %v", faller.Position)
+	}
+
+	if backend.Stage == nil || backend.Stage.Bounds() != image.Rect(0, 0, 64, 64) {
+		t.Fatal("expected a 64x64 stage to be kept")
+	}
+
+	// Y is flipped when painting, so the floor tile fills the bottom 16 rows
+	expectedPixels := map[image.Point]color.RGBA{
+		{X: 40, Y: 10}: {126, 192, 238, 255}, // background
+		{X: 2, Y: 50}:  {126, 192, 238, 255}, // transparent half of the floor tile
+		{X: 8, Y: 50}:  {255, 0, 0, 255},     // floor tile
+		{X: 12, Y: 50}: {0, 0, 255, 255},     // floor tile
+		{X: 16, Y: 40}: {255, 0, 0, 255},     // faller, a tile width to the right
+		{X: 22, Y: 40}: {0, 0, 255, 255},     // faller
+	}
+
+	for point, expected := range expectedPixels {
+
+		if actual := backend.Stage.RGBAAt(point.X, point.Y); actual != expected {
+			t.Errorf("expected %v at %v, got %v", expected, point, actual)
+		}
+	}
+}
+
+func TestHeadlessBackendStopsWithContext(t *testing.T) {
+
+	game, _ := headlessGame(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	game.Backend = &HeadlessBackend{
+		Frames: 1000,
+		FrameHandler: func(stage *image.RGBA, frameNumber int) {
+			if frameNumber == 4 {
+				cancel()
+			}
+		},
+	}
+
+	if err := game.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if game.CurrentFrame != 5 {
+		t.Fatalf("expected the game to stop after 5 frames, it painted %d", game.CurrentFrame)
+	}
+}
+
+func TestHeadlessBackendRunsUntilStopped(t *testing.T) {
+
+	game, _ := headlessGame(t)
+	paintedFrames := 0
+
+	game.Backend = &HeadlessBackend{
+		RunUntilStopped: true,
+		FrameHandler: func(stage *image.RGBA, frameNumber int) {
+
+			paintedFrames++
+
+			if frameNumber == 99 {
+				game.Stop()
+			}
+		},
+	}
+
+	if err := game.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if paintedFrames != 100 {
+		t.Fatalf("expected the game to run until stopped after 100 frames, it painted %d", paintedFrames)
+	}
+}
+
+func TestHeadlessBackendWithoutFramesReturns(t *testing.T) {
+
+	levels := []*Level{{}}
+	game := CreateGameWithBackend(&HeadlessBackend{}, "Headless", 64, 64, 1, 60, nil, nil, levels)
+
+	if game.CurrentFrame != 0 {
+		t.Fatalf("expected no frames to be painted, %d were", game.CurrentFrame)
+	}
+}
@@ -14,10 +14,36 @@ type Level struct {
 	Game             *Game
 	PaintOffset      Vector
 	BeforePaint      BeforePaint
+	OnEnter          LevelHook
+	OnExit           LevelHook
+	Factory          LevelFactory
+	PaletteCycles    []*PaletteCycle
+	spatialHash      *SpatialHash
 }
 
-// Repaint redraws the entire level for a new game
-func (level *Level) Repaint(stage *image.RGBA) {
+// NewLevelFromFactory builds a level from a factory, remembering the factory
+// so that the level can be rebuilt with fresh game objects when restarted
+func NewLevelFromFactory(factory LevelFactory) *Level {
+
+	level := factory()
+	level.Factory = factory
+
+	return level
+}
+
+// Update advances the level's simulation by a single fixed tick
+func (level *Level) Update() {
+
+	// Rotate any cycling palettes ready for the next repaint
+	if level.Game != nil {
+		for _, paletteCycle := range level.PaletteCycles {
+			paletteCycle.Advance(level.Game.TickDuration())
+		}
+	}
+
+	// Bring the spatial index up to date with objects added, removed or moved
+	// since the last tick, after which moving objects update their own entries
+	level.SpatialIndex()
 
 	// Figure out where all the floor objects are
 	level.AssignFloors()
@@ -25,10 +51,7 @@ func (level *Level) Repaint(stage *image.RGBA) {
 	// Figure out which objects are colliding
 	level.CalculateCollisions()
 
-	// Paint the background color
-	draw.Draw(stage, stage.Bounds(), &image.Uniform{level.BackgroundColour}, image.ZP, draw.Src)
-
-	// Update each game object
+	// Move each game object
 	for _, gameObject := range level.GameObjects {
 		// Skip hidden objects
 		if gameObject.IsHidden == true {
@@ -37,47 +60,106 @@ func (level *Level) Repaint(stage *image.RGBA) {
 
 		gameObject.Level = level
 
+		gameObject.PreviousPosition = gameObject.Position
+		gameObject.hasPreviousPosition = true
+
 		gameObject.RecalculatePosition(level.Gravity)
 
+		if gameObject.StateMachine != nil {
+			gameObject.updateStateMachine()
+		}
+
+		if level.Game != nil {
+			gameObject.advanceAnimation(level.Game.TickDuration())
+			gameObject.advancePaletteEffect(level.Game.TickDuration())
+		}
+
+		// Stop anything that moved through a floor or solid object this tick
+		// and keep the spatial index in step with it
+		if gameObject.IsInteractive == true {
+			level.SweepMovement(gameObject)
+			level.index().Update(gameObject)
+		}
+
 		if gameObject.Direction == DirLeft {
 			gameObject.IsFlipped = true
 		} else if gameObject.Direction == DirRight {
 			gameObject.IsFlipped = false
 		}
-
-		// 0 is at the bottom, so flip the Y axis to paint correctly
-		invertedY := level.Game.Height - int(gameObject.Position.Y) - gameObject.Height()
-		paintY := invertedY + int(level.PaintOffset.Y)
-		paintX := int(gameObject.Position.X) - int(level.PaintOffset.X)
-
-		gameObject.CurrentSprite().AddToCanvas(stage, paintX, paintY, gameObject.IsFlipped)
 	}
+
+	// Push anything that moved into a solid object back out of it
+	level.ResolveSolidCollisions()
 }
 
-// AssignFloors iterates through all objects in the level and defines which
-// object beneath them (if any) should be considered their 'floor' object,
-// setting its top edge as the lowest point that the object can fall
-func (level *Level) AssignFloors() {
+// Repaint redraws the entire level, placing each game object between its
+// previous and current position according to the game's interpolation
+func (level *Level) Repaint(stage *image.RGBA) {
 
-	floorXCoords := map[int][]*GameObject{}
+	// Paint the background color
+	draw.Draw(stage, stage.Bounds(), &image.Uniform{level.BackgroundColour}, image.ZP, draw.Src)
 
-	// Make a map of each object's possible X positions
+	// Paint each game object
 	for _, gameObject := range level.GameObjects {
-
-		// Skip hidden, non-interactive and non-floor objects
-		if gameObject.IsHidden == true || gameObject.IsInteractive == false || gameObject.IsFloor == false {
+		// Skip hidden objects
+		if gameObject.IsHidden == true {
 			continue
 		}
 
-		for i := 0; i < gameObject.Width(); i++ {
+		gameObject.Level = level
 
-			xPos := i + int(gameObject.Position.X)
-			floorXCoords[xPos] = append(floorXCoords[xPos], gameObject)
+		paintPosition := level.PaintPosition(gameObject)
 
-		}
+		gameObject.CurrentSprite().DrawToCanvas(stage, paintPosition.X, paintPosition.Y, gameObject.DrawOptions())
+	}
+}
+
+// PaintPosition gets the point on the stage at which the top left corner of a
+// game object is painted
+func (level *Level) PaintPosition(gameObject *GameObject) image.Point {
 
+	position := gameObject.RenderPosition()
+
+	// 0 is at the bottom, so flip the Y axis to paint correctly
+	invertedY := level.Game.Height - int(position.Y) - gameObject.Height()
+	paintY := invertedY + int(level.PaintOffset.Y)
+	paintX := int(position.X) - int(level.PaintOffset.X)
+
+	return image.Pt(paintX, paintY)
+}
+
+// SpatialIndex gets the level's spatial hash of interactive game objects,
+// bringing it up to date with the level's objects first
+func (level *Level) SpatialIndex() *SpatialHash {
+
+	if level.spatialHash == nil {
+		level.spatialHash = NewSpatialHash(SpatialHashCellSize)
+	}
+
+	level.spatialHash.sync(level.GameObjects)
+
+	return level.spatialHash
+}
+
+// index gets the level's spatial hash as it stands, only syncing it if it has
+// never been built. Objects that move during a tick update their own entries
+func (level *Level) index() *SpatialHash {
+
+	if level.spatialHash == nil {
+		return level.SpatialIndex()
 	}
 
+	return level.spatialHash
+}
+
+// AssignFloors iterates through all objects in the level and defines which
+// object beneath them (if any) should be considered their 'floor' object,
+// setting its top edge as the lowest point that the object can fall. Floors
+// are looked up in the spatial index as it was last synced
+func (level *Level) AssignFloors() {
+
+	index := level.index()
+
 	// Find the objects that sit beneath every other object
 	for _, gameObject := range level.GameObjects {
 
@@ -86,109 +168,176 @@ func (level *Level) AssignFloors() {
 			continue
 		}
 
-		highestFloorObject := float64(0 - gameObject.Height())
-
-		for i := 0; i < gameObject.Width(); i++ {
-
-			xPos := i + int(gameObject.Position.X)
-
-			if floorObjects, ok := floorXCoords[xPos]; ok {
-
-				// Find the one that is highest while still being lower than
-				// the object itself
-				for _, floorObject := range floorObjects {
+		// Floors are found beneath the object's hitbox, which may sit above
+		// the bottom of its sprite
+		box := gameObject.bounds()
+		hitboxOffsetY := box.Y - gameObject.Position.Y
+		highestFloorObject := float64(0-gameObject.Height()) + hitboxOffsetY
+		minX := int(box.X)
+		maxX := minX + int(box.Width)
 
-					floorObjectTop := (floorObject.Position.Y + float64(floorObject.Height()))
+		for _, floorObject := range index.QueryBelow(box.X, box.Y, box.Width, 0) {
 
-					if floorObjectTop <= gameObject.Position.Y {
+			// Skip non-floor objects (solid objects can always be stood on)
+			// and those on layers the object doesn't collide with
+			if floorObject == gameObject || floorObject.IsHidden == true || (floorObject.IsFloor == false && floorObject.IsSolid == false) || gameObject.CollidesWith(floorObject) == false {
+				continue
+			}
 
-						if floorObjectTop > highestFloorObject {
-							highestFloorObject = floorObjectTop
-						}
+			// Skip floors that don't share a pixel column with the object
+			floorBox := floorObject.bounds()
+			floorMinX := int(floorBox.X)
+			floorMaxX := floorMinX + int(floorBox.Width)
 
-					}
+			if floorMaxX <= minX || floorMinX >= maxX {
+				continue
+			}
 
-				}
+			// Find the one that is highest while still being lower than the
+			// object itself
+			floorObjectTop := (floorBox.Y + floorBox.Height)
 
+			if floorObjectTop <= box.Y && floorObjectTop > highestFloorObject {
+				highestFloorObject = floorObjectTop
 			}
-
 		}
 
-		gameObject.FloorY = highestFloorObject
+		gameObject.FloorY = highestFloorObject - hitboxOffsetY
 
 	}
 
 }
 
 // CalculateCollisions iterates via all objects in the level and defines which
-// objects (if any) intersect them
+// objects (if any) intersect them, looking them up in the spatial index as it
+// was last synced
 func (level *Level) CalculateCollisions() {
 
-	xCoords := map[int][]*GameObject{}
+	index := level.index()
 
-	// Make a map of each object's possible x positions
 	for _, gameObject := range level.GameObjects {
 
-		// Skip hidden of each object's possible X positions
+		// Skip hidden and non-interactive objects
 		if gameObject.IsHidden == true || gameObject.IsInteractive == false {
 			continue
 		}
 
-		for i := 0; i < gameObject.Width(); i++ {
-			xPos := i + int(gameObject.Position.X)
-			xCoords[xPos] = append(xCoords[xPos], gameObject)
+		box := gameObject.bounds()
+		gameObjectXmin := int(box.X)
+		gameObjectXmax := gameObjectXmin + int(box.Width)
+		gameObjectYmin := box.Y
+		gameObjectYmax := gameObjectYmin + box.Height
+		intersections := []*GameObject{}
+
+		for _, intersectingObject := range index.query(box) {
+
+			// Ignore the object itself, anything hidden by an earlier collision
+			// handler and anything on a layer the object doesn't collide with
+			if intersectingObject == gameObject || intersectingObject.IsHidden == true || intersectingObject.IsInteractive == false || gameObject.CollidesWith(intersectingObject) == false {
+				continue
+			}
+
+			intersectingBox := intersectingObject.bounds()
+			intersectingObjectXMin := int(intersectingBox.X)
+			intersectingObjectXMax := intersectingObjectXMin + int(intersectingBox.Width)
+			intersectingObjectYMin := intersectingBox.Y
+			intersectingObjectYMax := intersectingObjectYMin + intersectingBox.Height
+
+			if gameObjectXmin >= intersectingObjectXMax || gameObjectXmax <= intersectingObjectXMin {
+				continue
+			}
+
+			if gameObjectYmin >= intersectingObjectYMax || gameObjectYmax <= intersectingObjectYMin {
+				continue
+			}
+
+			// Pixel-perfect objects only collide where opaque pixels touch
+			if (gameObject.IsPixelPerfect == true || intersectingObject.IsPixelPerfect == true) && gameObject.pixelsOverlap(intersectingObject) == false {
+				continue
+			}
+
+			intersections = append(intersections, intersectingObject)
 		}
-	}
 
-	// Find objects that also intersect on the Y axis
-	for _, gameObject := range level.GameObjects {
+		// Let the game know that there have been collisions
+		for _, collidingObject := range intersections {
 
-		intersections := map[*GameObject]bool{}
-		gameObjectYmin := gameObject.Position.Y
-		gameObjectYmax := gameObjectYmin + float64(gameObject.Height())
+			gameObject.CollisionHandler(gameObject, Collision{
+				GameObject: collidingObject,
+				Edge:       gameObject.GetCollisionEdge(collidingObject),
+			})
+		}
 
-		for i := 0; i < gameObject.Width(); i++ {
+	}
 
-			xPos := i + int(gameObject.Position.X)
+}
 
-			if intersectingObjects, ok := xCoords[xPos]; ok {
+// ResolveSolidCollisions pushes moving objects out of any solid objects they
+// have moved into, using the edge they collided on to decide which way to push.
+// Walls push objects back out sideways, stop them walking into the wall and
+// emit EventWallCollision. Ceilings push objects back down, stopping their
+// upward velocity and emitting EventCeilingCollision
+func (level *Level) ResolveSolidCollisions() {
 
-				for _, intersectingObject := range intersectingObjects {
+	index := level.index()
 
-					// Ignore the object itself
-					if intersectingObject == gameObject {
-						continue
-					}
+	for _, gameObject := range level.GameObjects {
 
-					// Skip the object if it has already been stored
-					if _, ok := intersections[intersectingObject]; ok {
-						continue
-					}
+		// Skip hidden, non-interactive and immovable objects
+		if gameObject.IsHidden == true || gameObject.IsInteractive == false || (gameObject.Mass == 0 && gameObject.Velocity == Vector{}) {
+			continue
+		}
 
-					intersectingObjectYMin := intersectingObject.Position.Y
-					intersectingObjectYMax := intersectingObjectYMin + float64(intersectingObject.Height())
+		for _, solidObject := range index.query(gameObject.bounds()) {
 
-					if (gameObjectYmin >= intersectingObjectYMax || gameObjectYmax <= intersectingObjectYMin) == false {
-						intersections[intersectingObject] = true
-					}
-				}
+			if solidObject == gameObject || solidObject.IsSolid == false || solidObject.IsHidden == true || solidObject.IsInteractive == false || gameObject.CollidesWith(solidObject) == false {
+				continue
+			}
+
+			overlapX, overlapY := gameObject.overlap(solidObject)
 
+			if overlapX <= 0 || overlapY <= 0 {
+				continue
 			}
 
-		}
+			edge := gameObject.GetCollisionEdge(solidObject)
 
-		// Let the game know that there have been collisions
-		if len(intersections) > 0 {
+			// Corners are resolved along whichever axis has the least
+			// penetration
+			switch edge {
+			case EdgeTopLeft, EdgeTopRight, EdgeBottomLeft, EdgeBottomRight:
+
+				isLeft := edge == EdgeTopLeft || edge == EdgeBottomLeft
+				isTop := edge == EdgeTopLeft || edge == EdgeTopRight
+
+				if overlapX < overlapY {
+					edge = EdgeRight
+					if isLeft == true {
+						edge = EdgeLeft
+					}
+				} else {
+					edge = EdgeBottom
+					if isTop == true {
+						edge = EdgeTop
+					}
+				}
 
-			for collidingObject := range intersections {
+			case EdgeNone:
 
-				gameObject.CollisionHandler(gameObject, Collision{
-					GameObject: collidingObject,
-					Edge:       gameObject.GetCollisionEdge(collidingObject),
-				})
+				if overlapX < overlapY {
+					edge = EdgeRight
+					if gameObject.bounds().X < solidObject.bounds().X {
+						edge = EdgeLeft
+					}
+				} else {
+					edge = EdgeBottom
+					if gameObject.bounds().Y > solidObject.bounds().Y {
+						edge = EdgeTop
+					}
+				}
 			}
-		}
 
+			gameObject.pushOutOf(solidObject, edge)
+		}
 	}
-
 }
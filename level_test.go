@@ -0,0 +1,300 @@
+package engine
+
+import (
+	"image/color"
+	"testing"
+)
+
+// testPalette is a palette with a transparent slot and a few opaque colours
+var testPalette = &Palette{
+	"0": color.RGBA{0, 0, 0, 0},
+	"1": color.RGBA{255, 0, 0, 255},
+	"2": color.RGBA{0, 255, 0, 255},
+	"3": color.RGBA{0, 0, 255, 255},
+}
+
+// testSprite creates a 16x16 sprite that is transparent down its left half
+// and uses each opaque colour of testPalette across its right half
+func testSprite(tb testing.TB) *Sprite {
+
+	scanlines := []int{}
+
+	for y := 0; y < 16; y++ {
+		scanlines = append(scanlines, 0x00000000, 0x11223333)
+	}
+
+	sprite, err := CreateSprite(testPalette, scanlines)
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return sprite
+}
+
+// testObject creates an interactive game object drawn with a sprite
+func testObject(sprite SpriteInterface, x float64, y float64) *GameObject {
+
+	return &GameObject{
+		CurrentState: "default",
+		States: GameObjectStates{
+			"default": SpriteSeries{Sprites: []SpriteInterface{sprite}},
+		},
+		Position:         Vector{X: x, Y: y},
+		IsInteractive:    true,
+		DynamicData:      DynamicData{},
+		EventHandler:     func(eventCode int, gameObject *GameObject) {},
+		CollisionHandler: func(gameObject *GameObject, collision Collision) {},
+	}
+}
+
+// benchmarkLevel builds a level of the given number of floor tiles laid out
+// in rows, with a falling object above every tenth tile
+func benchmarkLevel(tb testing.TB, tiles int) *Level {
+
+	sprite := testSprite(tb)
+	gameObjects := []*GameObject{}
+
+	for i := 0; i < tiles; i++ {
+
+		tile := testObject(sprite, float64((i%100)*16), float64((i/100)*64))
+		tile.IsFloor = true
+		gameObjects = append(gameObjects, tile)
+
+		if i%10 == 0 {
+			faller := testObject(sprite, float64((i%100)*16)+4, float64((i/100)*64)+24)
+			faller.Mass = 1
+			gameObjects = append(gameObjects, faller)
+		}
+	}
+
+	return &Level{Gravity: 1, GameObjects: gameObjects}
+}
+
+func TestAssignFloorsMatchesPixelColumns(t *testing.T) {
+
+	level := benchmarkLevel(t, 600)
+	level.AssignFloors()
+
+	expected := map[*GameObject]float64{}
+
+	for _, gameObject := range level.GameObjects {
+		expected[gameObject] = gameObject.FloorY
+		gameObject.FloorY = 0
+	}
+
+	pixelColumnAssignFloors(level)
+
+	for _, gameObject := range level.GameObjects {
+
+		if gameObject.Mass != 0 && gameObject.FloorY != expected[gameObject] {
+			t.Fatalf("object at %v has floor %v, per-pixel columns give %v", gameObject.Position, expected[gameObject], gameObject.FloorY)
+		}
+	}
+}
+
+func BenchmarkAssignFloors(b *testing.B) {
+
+	level := benchmarkLevel(b, 600)
+	level.AssignFloors()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		level.AssignFloors()
+	}
+}
+
+func BenchmarkAssignFloorsPixelColumns(b *testing.B) {
+
+	level := benchmarkLevel(b, 600)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		pixelColumnAssignFloors(level)
+	}
+}
+
+func BenchmarkCalculateCollisions(b *testing.B) {
+
+	level := benchmarkLevel(b, 600)
+	level.CalculateCollisions()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		level.CalculateCollisions()
+	}
+}
+
+func BenchmarkCalculateCollisionsPixelColumns(b *testing.B) {
+
+	level := benchmarkLevel(b, 600)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		pixelColumnCalculateCollisions(level)
+	}
+}
+
+// pixelColumnAssignFloors is the floor assignment the spatial hash replaced,
+// which maps every pixel column of every floor each time it runs
+func pixelColumnAssignFloors(level *Level) {
+
+	floorXCoords := map[int][]*GameObject{}
+
+	for _, gameObject := range level.GameObjects {
+
+		if gameObject.IsHidden == true || gameObject.IsInteractive == false || gameObject.IsFloor == false {
+			continue
+		}
+
+		for i := 0; i < gameObject.Width(); i++ {
+			xPos := i + int(gameObject.Position.X)
+			floorXCoords[xPos] = append(floorXCoords[xPos], gameObject)
+		}
+	}
+
+	for _, gameObject := range level.GameObjects {
+
+		if gameObject.Mass == 0 || gameObject.IsInteractive == false {
+			continue
+		}
+
+		highestFloorObject := float64(0 - gameObject.Height())
+
+		for i := 0; i < gameObject.Width(); i++ {
+
+			for _, floorObject := range floorXCoords[i+int(gameObject.Position.X)] {
+
+				floorObjectTop := (floorObject.Position.Y + float64(floorObject.Height()))
+
+				if floorObjectTop <= gameObject.Position.Y && floorObjectTop > highestFloorObject {
+					highestFloorObject = floorObjectTop
+				}
+			}
+		}
+
+		gameObject.FloorY = highestFloorObject
+	}
+}
+
+// pixelColumnCalculateCollisions is the collision detection the spatial hash
+// replaced, which maps every pixel column of every object each time it runs
+func pixelColumnCalculateCollisions(level *Level) {
+
+	xCoords := map[int][]*GameObject{}
+
+	for _, gameObject := range level.GameObjects {
+
+		if gameObject.IsHidden == true || gameObject.IsInteractive == false {
+			continue
+		}
+
+		for i := 0; i < gameObject.Width(); i++ {
+			xPos := i + int(gameObject.Position.X)
+			xCoords[xPos] = append(xCoords[xPos], gameObject)
+		}
+	}
+
+	for _, gameObject := range level.GameObjects {
+
+		intersections := map[*GameObject]bool{}
+		gameObjectYmin := gameObject.Position.Y
+		gameObjectYmax := gameObjectYmin + float64(gameObject.Height())
+
+		for i := 0; i < gameObject.Width(); i++ {
+
+			for _, intersectingObject := range xCoords[i+int(gameObject.Position.X)] {
+
+				if intersectingObject == gameObject || intersections[intersectingObject] == true {
+					continue
+				}
+
+				intersectingObjectYMin := intersectingObject.Position.Y
+				intersectingObjectYMax := intersectingObjectYMin + float64(intersectingObject.Height())
+
+				if (gameObjectYmin >= intersectingObjectYMax || gameObjectYmax <= intersectingObjectYMin) == false {
+					intersections[intersectingObject] = true
+				}
+			}
+		}
+
+		for collidingObject := range intersections {
+
+			gameObject.CollisionHandler(gameObject, Collision{
+				GameObject: collidingObject,
+				Edge:       gameObject.GetCollisionEdge(collidingObject),
+			})
+		}
+	}
+}
+
+func TestWallsStopWalkingObjects(t *testing.T) {
+
+	sprite := testSprite(t)
+
+	// A wall standing on the floor, to the right of a walking object
+	wall := testObject(sprite, 64, 16)
+	wall.IsSolid = true
+
+	walker := testObject(sprite, 16, 16)
+	walker.Mass = 1
+	walker.Velocity.X = 3
+	walker.Direction = DirRight
+	wallCollisions := 0
+
+	walker.EventHandler = func(eventCode int, gameObject *GameObject) {
+		if eventCode == EventWallCollision {
+			wallCollisions++
+		}
+	}
+
+	// Floor tiles along the bottom, so that the walker stays on its feet
+	gameObjects := []*GameObject{wall, walker}
+
+	for x := 0; x < 128; x += 16 {
+		tile := testObject(sprite, float64(x), 0)
+		tile.IsFloor = true
+		gameObjects = append(gameObjects, tile)
+	}
+
+	level := &Level{Gravity: 1, GameObjects: gameObjects}
+
+	for i := 0; i < 60; i++ {
+		level.Update()
+	}
+
+	if walker.Position.X != 48 {
+		t.Fatalf("expected the walker to stop against the wall at X 48, it is at %v", walker.Position.X)
+	}
+
+	if walker.Direction != DirStationary {
+		t.Fatalf("expected the walker to stop walking, its direction is %d", walker.Direction)
+	}
+
+	if wallCollisions != 1 {
+		t.Fatalf("expected a single wall collision, got %d", wallCollisions)
+	}
+}
+
+func TestUpdateSyncsSpatialIndexOnce(t *testing.T) {
+
+	level := benchmarkLevel(t, 100)
+
+	for i := 0; i < 3; i++ {
+		level.Update()
+	}
+
+	if level.spatialHash.syncStamp != 3 {
+		t.Fatalf("expected the spatial index to be synced once per update, it was synced %d times in 3", level.spatialHash.syncStamp)
+	}
+
+	// Objects added and removed between ticks are picked up by the next one
+	added := testObject(testSprite(t), 0, 500)
+	removed := level.GameObjects[0]
+	level.GameObjects = append(level.GameObjects[1:], added)
+	level.Update()
+
+	if level.spatialHash.Contains(added) == false || level.spatialHash.Contains(removed) == true {
+		t.Fatal("expected the next update to file added objects and drop removed ones")
+	}
+}
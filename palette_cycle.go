@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"image/color"
+	"time"
+)
+
+// PaletteCycle is a struct that defines a run of palette slots whose colours
+// rotate along the run at a fixed number of steps per second, animating every
+// sprite drawn with the palette (such as water, lava or glowing effects)
+// without needing extra frames. The palette is changed in place, so every
+// sprite sharing it cycles together
+type PaletteCycle struct {
+	Palette        *Palette
+	Slots          []string
+	StepsPerSecond float64
+	Reverse        bool
+	baseColours    []color.RGBA
+	elapsed        time.Duration
+}
+
+// Advance moves the cycle on by the elapsed time, updating the palette
+func (cycle *PaletteCycle) Advance(elapsed time.Duration) {
+
+	if cycle.Palette == nil || len(cycle.Slots) == 0 {
+		return
+	}
+
+	// Remember the original colours so the rotation never drifts
+	if cycle.baseColours == nil {
+		for _, slot := range cycle.Slots {
+			cycle.baseColours = append(cycle.baseColours, (*cycle.Palette)[slot])
+		}
+	}
+
+	cycle.elapsed += elapsed
+
+	slotCount := len(cycle.Slots)
+	offset := int(cycle.elapsed.Seconds()*cycle.StepsPerSecond) % slotCount
+
+	if cycle.Reverse == true {
+		offset = slotCount - offset
+	}
+
+	for i, slot := range cycle.Slots {
+		(*cycle.Palette)[slot] = cycle.baseColours[(i+offset)%slotCount]
+	}
+}
+
+// Reset puts the palette's original colours back and restarts the cycle
+func (cycle *PaletteCycle) Reset() {
+
+	for i, colour := range cycle.baseColours {
+		(*cycle.Palette)[cycle.Slots[i]] = colour
+	}
+
+	cycle.baseColours = nil
+	cycle.elapsed = 0
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"image/color"
+	"time"
+)
+
+// paletteEffect is a struct that defines a timed change to the colours a game
+// object is drawn in
+type paletteEffect struct {
+	colour   color.RGBA
+	isFade   bool
+	duration time.Duration
+	elapsed  time.Duration
+}
+
+// FlashPalette draws every visible pixel of the game object in a single
+// colour for a duration, such as a white flash when it is hit
+func (gameObject *GameObject) FlashPalette(colour color.RGBA, duration time.Duration) {
+
+	gameObject.paletteEffect = &paletteEffect{
+		colour:   colour,
+		duration: duration,
+	}
+}
+
+// FadePalette gradually blends the colours the game object is drawn in
+// towards a target colour over a duration, leaving them faded until the
+// effect is cleared
+func (gameObject *GameObject) FadePalette(target color.RGBA, duration time.Duration) {
+
+	gameObject.paletteEffect = &paletteEffect{
+		colour:   target,
+		isFade:   true,
+		duration: duration,
+	}
+}
+
+// ClearPaletteEffect stops any flash or fade on the game object
+func (gameObject *GameObject) ClearPaletteEffect() {
+	gameObject.paletteEffect = nil
+}
+
+// advancePaletteEffect moves the game object's palette effect on by the
+// elapsed time, ending flashes once their duration has passed
+func (gameObject *GameObject) advancePaletteEffect(elapsed time.Duration) {
+
+	effect := gameObject.paletteEffect
+
+	if effect == nil {
+		return
+	}
+
+	effect.elapsed += elapsed
+
+	if effect.isFade == false && effect.elapsed >= effect.duration {
+		gameObject.paletteEffect = nil
+	}
+}
+
+// paletteFilter gets the filter the game object's colours are drawn through,
+// applying any flash or fade on top of its own palette filter
+func (gameObject *GameObject) paletteFilter() PaletteFilter {
+
+	effect := gameObject.paletteEffect
+
+	if effect == nil {
+		return gameObject.PaletteFilter
+	}
+
+	effectFilter := FlashFilter(effect.colour)
+
+	if effect.isFade == true {
+
+		amount := 1.0
+
+		if effect.duration > 0 && effect.elapsed < effect.duration {
+			amount = float64(effect.elapsed) / float64(effect.duration)
+		}
+
+		effectFilter = FadeFilter(effect.colour, amount)
+	}
+
+	if gameObject.PaletteFilter == nil {
+		return effectFilter
+	}
+
+	ownFilter := gameObject.PaletteFilter
+
+	return func(colour color.RGBA) color.RGBA {
+		return effectFilter(ownFilter(colour))
+	}
+}
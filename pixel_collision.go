@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"image"
+	"math"
+	"sync"
+)
+
+// spriteMask is a struct that defines which pixels of a sprite frame are
+// opaque, so that pixel-perfect collisions don't need to redraw the sprite
+type spriteMask struct {
+	width  int
+	height int
+	opaque []bool
+}
+
+// spriteMaskKey is a struct that identifies a cached sprite mask by the
+// geometry it was drawn with and which of its palette slots were transparent
+type spriteMaskKey struct {
+	sprite           SpriteInterface
+	transform        spriteTransform
+	transparentSlots uint16
+}
+
+// spriteMasks caches the masks of every sprite frame that has taken part in
+// a pixel-perfect collision check
+var spriteMasks = struct {
+	sync.Mutex
+	masks map[spriteMaskKey]*spriteMask
+}{masks: map[spriteMaskKey]*spriteMask{}}
+
+// transparentSlots gets a bit for each palette slot a sprite is drawn
+// transparent in once the draw options' palette override and remapping are
+// applied. Sprite groups are cut from one sheet, so take the palette of their
+// first sprite
+func transparentSlots(sprite SpriteInterface, options DrawOptions) uint16 {
+
+	var palette *Palette
+
+	switch sprite := sprite.(type) {
+	case *Sprite:
+		palette = sprite.Palette
+	case *SpriteGroup:
+		if len(*sprite.Sprites) > 0 {
+			palette = (*sprite.Sprites)[0].Palette
+		}
+	}
+
+	slots := uint16(0)
+
+	for i, colour := range options.colours(palette) {
+		if colour.A == 0 {
+			slots |= 1 << uint(i)
+		}
+	}
+
+	return slots
+}
+
+// getSpriteMask gets the (cached) opaque pixel mask of a sprite frame drawn
+// with a set of draw options, built from the alpha channel of the colours it
+// is drawn in after any palette override and remapping
+func getSpriteMask(sprite SpriteInterface, options DrawOptions) *spriteMask {
+
+	// Filters only tint colours for effects, so don't change what collides
+	options = DrawOptions{
+		FlipHorizontal: options.FlipHorizontal,
+		FlipVertical:   options.FlipVertical,
+		Rotation:       options.Rotation,
+		Scale:          options.Scale,
+		Palette:        options.Palette,
+		PaletteRemap:   options.PaletteRemap,
+	}
+	key := spriteMaskKey{sprite, options.transform(), transparentSlots(sprite, options)}
+
+	spriteMasks.Lock()
+	defer spriteMasks.Unlock()
+
+	if mask, ok := spriteMasks.masks[key]; ok {
+		return mask
+	}
+
+	width, height := options.Size(sprite.Width(), sprite.Height())
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	sprite.DrawToCanvas(canvas, 0, 0, options)
+
+	mask := &spriteMask{
+		width:  width,
+		height: height,
+		opaque: make([]bool, width*height),
+	}
+
+	for y := 0; y < mask.height; y++ {
+		for x := 0; x < mask.width; x++ {
+			mask.opaque[(y*mask.width)+x] = canvas.RGBAAt(x, y).A > 0
+		}
+	}
+
+	spriteMasks.masks[key] = mask
+
+	return mask
+}
+
+// spriteMask gets the opaque pixel mask of the game object's current sprite
+// frame, or nil for objects that aren't pixel-perfect
+func (gameObject *GameObject) spriteMask() *spriteMask {
+
+	if gameObject.IsPixelPerfect == false {
+		return nil
+	}
+
+	return getSpriteMask(gameObject.CurrentSprite(), gameObject.DrawOptions())
+}
+
+// isOpaqueAt determines whether the game object's sprite mask has an opaque
+// pixel at a point in level coordinates. Objects without a mask are treated
+// as solid across their whole sprite
+func (gameObject *GameObject) isOpaqueAt(mask *spriteMask, x int, y int) bool {
+
+	originX := int(gameObject.Position.X)
+	originY := int(gameObject.Position.Y)
+
+	if mask == nil {
+		return x >= originX && x < originX+gameObject.Width() && y >= originY && y < originY+gameObject.Height()
+	}
+
+	// Sprites are drawn from the top down while levels count from the bottom up
+	column := x - originX
+	row := mask.height - 1 - (y - originY)
+
+	if column < 0 || column >= mask.width || row < 0 || row >= mask.height {
+		return false
+	}
+
+	return mask.opaque[(row*mask.width)+column]
+}
+
+// pixelsOverlap determines whether two game objects whose hitboxes intersect
+// have opaque pixels in the same place within that intersection
+func (gameObject *GameObject) pixelsOverlap(otherObject *GameObject) bool {
+
+	box := gameObject.bounds()
+	otherBox := otherObject.bounds()
+
+	minX := int(math.Max(box.X, otherBox.X))
+	maxX := int(math.Ceil(math.Min(box.X+box.Width, otherBox.X+otherBox.Width)))
+	minY := int(math.Max(box.Y, otherBox.Y))
+	maxY := int(math.Ceil(math.Min(box.Y+box.Height, otherBox.Y+otherBox.Height)))
+	mask := gameObject.spriteMask()
+	otherMask := otherObject.spriteMask()
+
+	for y := minY; y < maxY; y++ {
+		for x := minX; x < maxX; x++ {
+			if gameObject.isOpaqueAt(mask, x, y) == true && otherObject.isOpaqueAt(otherMask, x, y) == true {
+				return true
+			}
+		}
+	}
+
+	return false
+}
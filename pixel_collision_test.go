@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPixelsOverlapFollowsPaletteOverrides(t *testing.T) {
+
+	sprite := testSprite(t)
+
+	// Both objects are opaque down their right half, so overlap there
+	gameObject := testObject(sprite, 0, 0)
+	gameObject.IsPixelPerfect = true
+	otherObject := testObject(sprite, 4, 0)
+	otherObject.IsPixelPerfect = true
+
+	if gameObject.pixelsOverlap(otherObject) == false {
+		t.Fatal("expected the opaque halves of the objects to overlap")
+	}
+
+	// Drawing every colour transparent leaves nothing to collide with
+	otherObject.Palette = &Palette{
+		"1": color.RGBA{255, 0, 0, 0},
+		"2": color.RGBA{0, 255, 0, 0},
+		"3": color.RGBA{0, 0, 255, 0},
+	}
+
+	if gameObject.pixelsOverlap(otherObject) == true {
+		t.Fatal("expected pixels made transparent by a palette override not to overlap")
+	}
+
+	otherObject.Palette = nil
+	otherObject.PaletteRemap = map[string]string{"1": "0", "2": "0", "3": "0"}
+
+	if gameObject.pixelsOverlap(otherObject) == true {
+		t.Fatal("expected pixels remapped to a transparent slot not to overlap")
+	}
+
+	// Leaving the red slot opaque leaves a column to collide with
+	otherObject.PaletteRemap = map[string]string{"2": "0", "3": "0"}
+
+	if gameObject.pixelsOverlap(otherObject) == false {
+		t.Fatal("expected pixels left opaque by a palette remap to overlap")
+	}
+}
@@ -0,0 +1,367 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// bayerMatrix is the 4x4 threshold map used for ordered dithering
+var bayerMatrix = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// ditherSpread is how far (in each colour channel) ordered dithering nudges
+// pixels before they are matched to a palette colour
+const ditherSpread = 32
+
+// QuantisationReport is a struct that describes how much an image changed when
+// its colours were reduced, with Colours counting the transparent colour if
+// any pixels use it. Errors are distances in RGB space between the original
+// and new colour of each opaque pixel
+type QuantisationReport struct {
+	OriginalColours int
+	Colours         int
+	MeanError       float64
+	MaxError        float64
+}
+
+// QuantisationHandler is the signature for functions that are told how much
+// an image changed when the generator reduced its colours
+type QuantisationHandler func(report QuantisationReport)
+
+// colourCount is a struct that defines a colour and how many pixels use it
+type colourCount struct {
+	colour color.RGBA
+	count  int
+}
+
+// QuantiseImage reduces an image to no more than the given number of colours
+// using median cut, optionally with ordered dithering. Every pixel that is
+// less than half opaque becomes transparent, which takes up one of the colours
+// if there are any, and every other pixel is made fully opaque
+func QuantiseImage(img image.Image, colours int, dither bool) (*image.RGBA, QuantisationReport) {
+
+	bounds := img.Bounds()
+	histogram := opaqueHistogram(img)
+	opaquePixels := 0
+
+	for _, entry := range histogram {
+		opaquePixels += entry.count
+	}
+
+	if opaquePixels < bounds.Dx()*bounds.Dy() {
+		colours--
+	}
+
+	return remapImage(img, medianCut(histogram, colours), dither, len(histogram))
+}
+
+// quantiseToPalette reduces an image to the colours of a palette, optionally
+// with ordered dithering. Pixels that are less than half opaque become fully
+// transparent
+func quantiseToPalette(img image.Image, palette Palette, dither bool) (*image.RGBA, QuantisationReport) {
+
+	colours := []color.RGBA{}
+
+	for _, paletteSlot := range paletteSlots {
+
+		if colour, ok := palette[paletteSlot]; ok && colour.A == 255 {
+			colours = append(colours, colour)
+		}
+	}
+
+	return remapImage(img, colours, dither, len(opaqueHistogram(img)))
+}
+
+// opaqueHistogram counts how many pixels of an image use each colour, ignoring
+// pixels that will become transparent. Colours are sorted so that the same
+// image is always quantised the same way
+func opaqueHistogram(img image.Image) []colourCount {
+
+	bounds := img.Bounds()
+	counts := map[color.RGBA]int{}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+
+			if colour, ok := opaqueColourAt(img, x, y); ok {
+				counts[colour]++
+			}
+		}
+	}
+
+	histogram := []colourCount{}
+
+	for colour, count := range counts {
+		histogram = append(histogram, colourCount{colour, count})
+	}
+
+	sort.Slice(histogram, func(i int, j int) bool {
+		return packColour(histogram[i].colour) < packColour(histogram[j].colour)
+	})
+
+	return histogram
+}
+
+// medianCut splits the colours of a histogram into boxes, always splitting the
+// box with the widest range of a single channel at the median pixel along that
+// channel, and gets the average colour of each box
+func medianCut(histogram []colourCount, colours int) []color.RGBA {
+
+	if len(histogram) == 0 || colours <= 0 {
+		return []color.RGBA{}
+	}
+
+	boxes := [][]colourCount{histogram}
+
+	for len(boxes) < colours {
+
+		widestBox := -1
+		widestChannel := 0
+		widestRange := 0
+
+		for i, box := range boxes {
+
+			channel, channelRange := widestChannelOf(box)
+
+			if len(box) > 1 && channelRange > widestRange {
+				widestBox = i
+				widestChannel = channel
+				widestRange = channelRange
+			}
+		}
+
+		// Every box is down to a single colour
+		if widestBox == -1 {
+			break
+		}
+
+		box := boxes[widestBox]
+
+		sort.SliceStable(box, func(i int, j int) bool {
+			return channelOf(box[i].colour, widestChannel) < channelOf(box[j].colour, widestChannel)
+		})
+
+		split := medianIndex(box)
+		boxes[widestBox] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := []color.RGBA{}
+
+	for _, box := range boxes {
+		palette = append(palette, averageColour(box))
+	}
+
+	return palette
+}
+
+// widestChannelOf gets the channel (0 for red, 1 for green and 2 for blue)
+// whose values spread furthest across a box, and how far they spread
+func widestChannelOf(box []colourCount) (int, int) {
+
+	widestChannel := 0
+	widestRange := -1
+
+	for channel := 0; channel < 3; channel++ {
+
+		minValue := 255
+		maxValue := 0
+
+		for _, entry := range box {
+
+			value := channelOf(entry.colour, channel)
+
+			if value < minValue {
+				minValue = value
+			}
+
+			if value > maxValue {
+				maxValue = value
+			}
+		}
+
+		if maxValue-minValue > widestRange {
+			widestChannel = channel
+			widestRange = maxValue - minValue
+		}
+	}
+
+	return widestChannel, widestRange
+}
+
+// medianIndex gets the index a sorted box is split at, which is the first
+// colour past half of the box's pixels (but always leaves a colour each side)
+func medianIndex(box []colourCount) int {
+
+	total := 0
+
+	for _, entry := range box {
+		total += entry.count
+	}
+
+	running := 0
+
+	for i, entry := range box {
+
+		running += entry.count
+
+		if running*2 >= total {
+			if i+1 >= len(box) {
+				return len(box) - 1
+			}
+
+			return i + 1
+		}
+	}
+
+	return len(box) / 2
+}
+
+// averageColour gets the average colour of a box, weighted by pixel count
+func averageColour(box []colourCount) color.RGBA {
+
+	var r, g, b, total int
+
+	for _, entry := range box {
+		r += int(entry.colour.R) * entry.count
+		g += int(entry.colour.G) * entry.count
+		b += int(entry.colour.B) * entry.count
+		total += entry.count
+	}
+
+	return color.RGBA{uint8((r + (total / 2)) / total), uint8((g + (total / 2)) / total), uint8((b + (total / 2)) / total), 255}
+}
+
+// remapImage redraws an image using only the given opaque colours (plus
+// transparency), matching each pixel to its nearest colour and reporting the
+// difference this made
+func remapImage(img image.Image, colours []color.RGBA, dither bool, originalColours int) (*image.RGBA, QuantisationReport) {
+
+	bounds := img.Bounds()
+	remapped := image.NewRGBA(bounds)
+	nearest := map[color.RGBA]color.RGBA{}
+	usedColours := map[color.RGBA]bool{}
+	report := QuantisationReport{OriginalColours: originalColours}
+	opaquePixels := 0
+	totalError := 0.0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+
+			colour, ok := opaqueColourAt(img, x, y)
+
+			if ok == false || len(colours) == 0 {
+				usedColours[color.RGBA{}] = true
+				continue
+			}
+
+			var match color.RGBA
+
+			if dither == true {
+				offset := ((bayerMatrix[y&3][x&3] + 0.5) / 16) - 0.5
+				match = nearestColour(colours, ditheredColour(colour, offset*ditherSpread))
+			} else if cached, ok := nearest[colour]; ok {
+				match = cached
+			} else {
+				match = nearestColour(colours, colour)
+				nearest[colour] = match
+			}
+
+			remapped.SetRGBA(x, y, match)
+			usedColours[match] = true
+
+			pixelError := colourDistance(colour, match)
+			totalError += pixelError
+			opaquePixels++
+
+			if pixelError > report.MaxError {
+				report.MaxError = pixelError
+			}
+		}
+	}
+
+	if opaquePixels > 0 {
+		report.MeanError = totalError / float64(opaquePixels)
+	}
+
+	report.Colours = len(usedColours)
+
+	return remapped, report
+}
+
+// nearestColour gets whichever of the colours is closest to a colour, taking
+// the first of any that are equally close
+func nearestColour(colours []color.RGBA, colour color.RGBA) color.RGBA {
+
+	nearest := colours[0]
+	nearestDistance := math.MaxFloat64
+
+	for _, candidate := range colours {
+
+		if distance := colourDistance(colour, candidate); distance < nearestDistance {
+			nearest = candidate
+			nearestDistance = distance
+		}
+	}
+
+	return nearest
+}
+
+// ditheredColour nudges each channel of a colour by an offset
+func ditheredColour(colour color.RGBA, offset float64) color.RGBA {
+
+	nudge := func(value uint8) uint8 {
+		return uint8(math.Max(0, math.Min(255, float64(value)+offset)))
+	}
+
+	return color.RGBA{nudge(colour.R), nudge(colour.G), nudge(colour.B), colour.A}
+}
+
+// colourDistance gets the distance between two colours in RGB space
+func colourDistance(a color.RGBA, b color.RGBA) float64 {
+
+	r := float64(a.R) - float64(b.R)
+	g := float64(a.G) - float64(b.G)
+	bl := float64(a.B) - float64(b.B)
+
+	return math.Sqrt((r * r) + (g * g) + (bl * bl))
+}
+
+// opaqueColourAt gets the colour of an image pixel made fully opaque, or
+// reports that the pixel is less than half opaque and will become transparent
+func opaqueColourAt(img image.Image, x int, y int) (color.RGBA, bool) {
+
+	colour := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+
+	if colour.A < 128 {
+		return color.RGBA{}, false
+	}
+
+	return color.RGBA{colour.R, colour.G, colour.B, 255}, true
+}
+
+// channelOf gets the red (0), green (1) or blue (2) channel of a colour
+func channelOf(colour color.RGBA, channel int) int {
+
+	switch channel {
+	case 0:
+		return int(colour.R)
+	case 1:
+		return int(colour.G)
+	}
+
+	return int(colour.B)
+}
+
+// packColour packs the channels of a colour into a single number for sorting
+func packColour(colour color.RGBA) uint32 {
+	return (uint32(colour.R) << 16) | (uint32(colour.G) << 8) | uint32(colour.B)
+}
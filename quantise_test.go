@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// colourBlocks creates an image of 4x4 blocks of well spread opaque colours
+func colourBlocks(colours int) *image.RGBA {
+
+	img := image.NewRGBA(image.Rect(0, 0, 4*colours, 4))
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4*colours; x++ {
+			block := x / 4
+			img.SetRGBA(x, y, color.RGBA{uint8((block % 4) * 80), uint8(((block / 4) % 4) * 80), uint8((block % 3) * 120), 255})
+		}
+	}
+
+	return img
+}
+
+func TestQuantiseImageKeepsSixteenOpaqueColours(t *testing.T) {
+
+	img := colourBlocks(16)
+	quantised, report := QuantiseImage(img, 16, false)
+
+	for i := range img.Pix {
+		if quantised.Pix[i] != img.Pix[i] {
+			t.Fatalf("expected a 16 colour image to pass through unchanged, pixel %d changed", i/4)
+		}
+	}
+
+	if report.OriginalColours != 16 || report.Colours != 16 || report.MeanError != 0 || report.MaxError != 0 {
+		t.Fatalf("expected an unchanged report of 16 colours, got %+v", report)
+	}
+
+	// Transparency takes one of the colours once some pixels need it
+	img.SetRGBA(0, 0, color.RGBA{})
+	_, report = QuantiseImage(img, 16, false)
+
+	if report.Colours != 16 || report.MaxError == 0 {
+		t.Fatalf("expected 15 opaque colours and a transparent one, got %+v", report)
+	}
+}
+
+func TestQuantiseImageReportsGradient(t *testing.T) {
+
+	img := image.NewRGBA(image.Rect(0, 0, 256, 4))
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 256; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(x), uint8(x), 255})
+		}
+	}
+
+	// 16 even steps of grey leave each channel no more than half a step of
+	// 16 away, plus up to the dither spread when dithering
+	maxChannelErrors := map[bool]float64{false: 8, true: 8 + (ditherSpread / 2)}
+
+	for dither, maxChannelError := range maxChannelErrors {
+
+		quantised, report := QuantiseImage(img, 16, dither)
+		used := map[color.RGBA]bool{}
+
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 256; x++ {
+				used[quantised.RGBAAt(x, y)] = true
+			}
+		}
+
+		if report.OriginalColours != 256 || report.Colours != len(used) || report.Colours > 16 {
+			t.Errorf("expected 256 colours reduced to the %d used (no more than 16) when dithering is %v, got %+v", len(used), dither, report)
+		}
+
+		if report.MeanError <= 0 || report.MeanError > report.MaxError || report.MaxError > maxChannelError*math.Sqrt(3) {
+			t.Errorf("expected small non-zero errors when dithering is %v, got %+v", dither, report)
+		}
+	}
+}
+
+func TestMedianCut(t *testing.T) {
+
+	histogram := []colourCount{
+		{color.RGBA{0, 0, 0, 255}, 3},
+		{color.RGBA{10, 0, 0, 255}, 1},
+		{color.RGBA{200, 0, 0, 255}, 4},
+	}
+
+	// The widest gap is split first, and boxes are averaged by pixel count
+	palette := medianCut(histogram, 2)
+	expected := []color.RGBA{{3, 0, 0, 255}, {200, 0, 0, 255}}
+
+	if len(palette) != 2 || palette[0] != expected[0] || palette[1] != expected[1] {
+		t.Fatalf("expected %v, got %v", expected, palette)
+	}
+
+	// Boxes can't be split past a single colour each
+	if palette := medianCut(histogram, 16); len(palette) != 3 {
+		t.Fatalf("expected 3 colours from a 3 colour histogram, got %v", palette)
+	}
+}
+
+func TestDitheringMixesPaletteColours(t *testing.T) {
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetRGBA(x, y, color.RGBA{120, 120, 120, 255})
+		}
+	}
+
+	palette := Palette{"0": color.RGBA{}, "1": color.RGBA{100, 100, 100, 255}, "2": color.RGBA{140, 140, 140, 255}}
+
+	for dither, expectedColours := range map[bool]int{false: 1, true: 2} {
+
+		quantised, _ := quantiseToPalette(img, palette, dither)
+		used := map[color.RGBA]bool{}
+
+		for i := 0; i < len(quantised.Pix); i += 4 {
+			used[color.RGBA{quantised.Pix[i], quantised.Pix[i+1], quantised.Pix[i+2], quantised.Pix[i+3]}] = true
+		}
+
+		if len(used) != expectedColours {
+			t.Errorf("expected %d colours when dithering is %v, got %v", expectedColours, dither, used)
+		}
+	}
+}
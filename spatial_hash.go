@@ -0,0 +1,216 @@
+package engine
+
+import "math"
+
+// SpatialHash is a struct that defines a uniform grid of cells that game
+// objects are filed into by the area they cover, so that the objects near a
+// point can be found without scanning every object in a level. Objects are
+// only moved between cells when the cells they cover change
+type SpatialHash struct {
+	CellSize   float64
+	cells      map[spatialCell][]*GameObject
+	entries    map[*GameObject]*spatialEntry
+	minCellY   int
+	queryStamp int
+	syncStamp  int
+}
+
+// spatialCell is a struct that defines the coordinates of a single cell
+type spatialCell struct {
+	X int
+	Y int
+}
+
+// spatialEntry is a struct that defines the range of cells an object covers
+type spatialEntry struct {
+	min        spatialCell
+	max        spatialCell
+	queryStamp int
+	syncStamp  int
+}
+
+// NewSpatialHash creates an empty spatial hash with cells of the given size
+func NewSpatialHash(cellSize float64) *SpatialHash {
+
+	return &SpatialHash{
+		CellSize: cellSize,
+		cells:    map[spatialCell][]*GameObject{},
+		entries:  map[*GameObject]*spatialEntry{},
+		minCellY: math.MaxInt32,
+	}
+}
+
+// Update files a game object into the cells it currently covers, adding it to
+// the hash if it isn't already there
+func (hash *SpatialHash) Update(gameObject *GameObject) {
+
+	min, max := hash.cellRange(gameObject.bounds())
+	entry, ok := hash.entries[gameObject]
+
+	if ok == true {
+
+		// Nothing to do if the object hasn't moved out of its cells
+		if entry.min == min && entry.max == max {
+			return
+		}
+
+		hash.unfile(gameObject, entry)
+
+	} else {
+
+		entry = &spatialEntry{}
+		hash.entries[gameObject] = entry
+	}
+
+	entry.min = min
+	entry.max = max
+
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			cell := spatialCell{x, y}
+			hash.cells[cell] = append(hash.cells[cell], gameObject)
+		}
+	}
+
+	if min.Y < hash.minCellY {
+		hash.minCellY = min.Y
+	}
+}
+
+// Remove takes a game object out of the hash
+func (hash *SpatialHash) Remove(gameObject *GameObject) {
+
+	if entry, ok := hash.entries[gameObject]; ok {
+		hash.unfile(gameObject, entry)
+		delete(hash.entries, gameObject)
+	}
+}
+
+// Contains determines whether a game object has been filed in the hash
+func (hash *SpatialHash) Contains(gameObject *GameObject) bool {
+
+	_, ok := hash.entries[gameObject]
+
+	return ok
+}
+
+// Query gets every game object filed in the cells that a box overlaps. The
+// objects returned are candidates, so callers still need to check whether
+// they actually intersect the box
+func (hash *SpatialHash) Query(x float64, y float64, width float64, height float64) []*GameObject {
+	return hash.query(bounds{X: x, Y: y, Width: width, Height: height})
+}
+
+// QueryBelow gets every game object filed in the cells beneath the top of a
+// box, down to the lowest object in the hash
+func (hash *SpatialHash) QueryBelow(x float64, y float64, width float64, height float64) []*GameObject {
+
+	min, max := hash.cellRange(bounds{X: x, Y: y, Width: width, Height: height})
+
+	if hash.minCellY < min.Y {
+		min.Y = hash.minCellY
+	}
+
+	return hash.collect(min, max)
+}
+
+// query gets every game object filed in the cells that a box overlaps
+func (hash *SpatialHash) query(box bounds) []*GameObject {
+
+	min, max := hash.cellRange(box)
+
+	return hash.collect(min, max)
+}
+
+// collect gets every game object in a range of cells, listing each only once
+func (hash *SpatialHash) collect(min spatialCell, max spatialCell) []*GameObject {
+
+	hash.queryStamp++
+	gameObjects := []*GameObject{}
+
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			for _, gameObject := range hash.cells[spatialCell{x, y}] {
+
+				entry := hash.entries[gameObject]
+
+				if entry.queryStamp == hash.queryStamp {
+					continue
+				}
+
+				entry.queryStamp = hash.queryStamp
+				gameObjects = append(gameObjects, gameObject)
+			}
+		}
+	}
+
+	return gameObjects
+}
+
+// cellRange gets the first and last cells covered by a box
+func (hash *SpatialHash) cellRange(box bounds) (spatialCell, spatialCell) {
+
+	min := spatialCell{
+		X: int(math.Floor(box.X / hash.CellSize)),
+		Y: int(math.Floor(box.Y / hash.CellSize)),
+	}
+
+	max := spatialCell{
+		X: int(math.Floor((box.X + box.Width) / hash.CellSize)),
+		Y: int(math.Floor((box.Y + box.Height) / hash.CellSize)),
+	}
+
+	return min, max
+}
+
+// unfile takes a game object out of the cells it was last filed in
+func (hash *SpatialHash) unfile(gameObject *GameObject, entry *spatialEntry) {
+
+	for x := entry.min.X; x <= entry.max.X; x++ {
+		for y := entry.min.Y; y <= entry.max.Y; y++ {
+
+			cell := spatialCell{x, y}
+			gameObjects := hash.cells[cell]
+
+			for i, cellObject := range gameObjects {
+
+				if cellObject == gameObject {
+					gameObjects[i] = gameObjects[len(gameObjects)-1]
+					gameObjects = gameObjects[:len(gameObjects)-1]
+					break
+				}
+			}
+
+			if len(gameObjects) == 0 {
+				delete(hash.cells, cell)
+			} else {
+				hash.cells[cell] = gameObjects
+			}
+		}
+	}
+}
+
+// sync brings the hash in line with a list of game objects, filing the
+// interactive, visible ones and removing any others
+func (hash *SpatialHash) sync(gameObjects []*GameObject) {
+
+	hash.syncStamp++
+
+	for _, gameObject := range gameObjects {
+
+		if gameObject.IsHidden == true || gameObject.IsInteractive == false {
+			continue
+		}
+
+		hash.Update(gameObject)
+		hash.entries[gameObject].syncStamp = hash.syncStamp
+	}
+
+	for gameObject, entry := range hash.entries {
+
+		if entry.syncStamp != hash.syncStamp {
+			hash.unfile(gameObject, entry)
+			delete(hash.entries, gameObject)
+		}
+	}
+}
@@ -2,18 +2,36 @@ package engine
 
 import (
 	"errors"
-	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
-	"strings"
+	"strconv"
 )
 
-// Sprite defines structure of single sprite
+// Sprite defines structure of single sprite. Each row of pixels is stored as
+// one or more hex-encoded groups of 8 palette slots, with any slots past the
+// sprite's width in a row's last group ignored. Sprites without a size set
+// are 16x16
 type Sprite struct {
-	Palette   *Palette
-	Scanlines *[]int
+	Palette      *Palette
+	Scanlines    *[]int
+	SpriteWidth  int
+	SpriteHeight int
+	pixels       []uint8
+	rasters      map[spriteRasterKey]*image.RGBA
 }
 
+// spriteRasterKey is a struct that identifies a cached rasterisation of a
+// sprite by the colours it was drawn in and how it was transformed
+type spriteRasterKey struct {
+	colours   [16]color.RGBA
+	transform spriteTransform
+}
+
+// maxSpriteRasters is the number of rasterisations a sprite keeps before its
+// cache is cleared
+const maxSpriteRasters = 32
+
 // CreateSprite object based on a set of hex-encoded scanlines
 func CreateSprite(palette *Palette, scanlines []int) (*Sprite, error) {
 	// Chec if scanlines consists of 32 hex groups
@@ -27,49 +45,143 @@ func CreateSprite(palette *Palette, scanlines []int) (*Sprite, error) {
 	}, nil
 }
 
+// CreateSpriteWithSize creates a sprite of any size based on a set of
+// hex-encoded scanlines, where each row of pixels is made up of as many groups
+// of 8 pixels as are needed to cover the sprite's width
+func CreateSpriteWithSize(palette *Palette, width int, height int, scanlines []int) (*Sprite, error) {
+
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("Sprite width and height must be greater than 0")
+	}
+
+	groupCount := scanlineGroupsPerRow(width) * height
+
+	if len(scanlines) != groupCount {
+		return nil, errors.New("Sprite of " + strconv.Itoa(width) + "x" + strconv.Itoa(height) + " requires " + strconv.Itoa(groupCount) + " hex groups, not " + strconv.Itoa(len(scanlines)))
+	}
+
+	return &Sprite{
+		Palette:      palette,
+		Scanlines:    &scanlines,
+		SpriteWidth:  width,
+		SpriteHeight: height,
+	}, nil
+}
+
 // Width gets the pixel width of the sprite
 func (sprite *Sprite) Width() int {
-	return 16
+
+	if sprite.SpriteWidth == 0 {
+		return 16
+	}
+
+	return sprite.SpriteWidth
 }
 
 // Height gets the pixel height of the sprite
 func (sprite *Sprite) Height() int {
-	return 16
+
+	if sprite.SpriteHeight == 0 {
+		return 16
+	}
+
+	return sprite.SpriteHeight
 }
 
 // AddToCanvas draws sprite to an existing image canvas
 func (sprite *Sprite) AddToCanvas(canvas *image.RGBA, targetX int, targetY int, mirrorImage bool) {
+	sprite.DrawToCanvas(canvas, targetX, targetY, DrawOptions{FlipHorizontal: mirrorImage})
+}
+
+// DrawToCanvas draws the sprite to an existing image canvas, transformed by
+// the draw options
+func (sprite *Sprite) DrawToCanvas(canvas *image.RGBA, targetX int, targetY int, options DrawOptions) {
+	width, height := options.Size(sprite.Width(), sprite.Height())
+
 	// Return early if sprite coordinates of the off-canvas
-	if targetX+sprite.Width() < 0 || targetX > canvas.Bounds().Max.X || targetY+sprite.Height() < 0 || targetY > canvas.Bounds().Max.Y {
+	if targetX+width < 0 || targetX > canvas.Bounds().Max.X || targetY+height < 0 || targetY > canvas.Bounds().Max.Y {
 		return
 	}
 
-	spriteImage := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	spriteImage := sprite.raster(options)
 
-	for i, scanlines := range *sprite.Scanlines {
-		y := i
+	draw.Draw(canvas, spriteImage.Bounds().Add(image.Pt(targetX, targetY)), spriteImage, image.ZP, draw.Over)
+}
+
+// raster gets the sprite drawn in its colours and transformed by the draw
+// options, rasterising it the first time that combination is used and reusing
+// the image after that. Rasters are keyed by colour rather than palette, so a
+// palette whose colours change is rasterised afresh
+func (sprite *Sprite) raster(options DrawOptions) *image.RGBA {
+	key := spriteRasterKey{
+		colours:   options.colours(sprite.Palette),
+		transform: options.transform(),
+	}
+
+	if spriteImage, ok := sprite.rasters[key]; ok {
+		return spriteImage
+	}
 
-		xOffset := 0
-		if (i % 2) != 0 {
-			y--
-			xOffset = 8
+	// Palettes that keep changing shouldn't grow the cache forever
+	if sprite.rasters == nil || len(sprite.rasters) >= maxSpriteRasters {
+		sprite.rasters = map[spriteRasterKey]*image.RGBA{}
+	}
+
+	width := sprite.Width()
+	height := sprite.Height()
+	scale := key.transform.scale
+	rasterWidth, rasterHeight := key.transform.size(width, height)
+	spriteImage := image.NewRGBA(image.Rect(0, 0, rasterWidth, rasterHeight))
+
+	for i, slot := range sprite.slotPixels() {
+		xPos, yPos := key.transform.point(i%width, i/width, width, height)
+
+		for y := 0; y < scale; y++ {
+			for x := 0; x < scale; x++ {
+				spriteImage.SetRGBA((xPos*scale)+x, (yPos*scale)+y, key.colours[slot])
+			}
 		}
+	}
+
+	sprite.rasters[key] = spriteImage
+
+	return spriteImage
+}
+
+// slotPixels gets the palette slot of every pixel in the sprite, row by row,
+// decoding the hex-encoded scanlines the first time they are needed
+func (sprite *Sprite) slotPixels() []uint8 {
+	if sprite.pixels != nil {
+		return sprite.pixels
+	}
 
-		y /= 2
+	width := sprite.Width()
+	groupsPerRow := scanlineGroupsPerRow(width)
+	pixels := make([]uint8, width*sprite.Height())
 
-		scanlinesString := fmt.Sprintf("%08x", scanlines)
-		scanlinePixels := strings.Split(scanlinesString, "")
+	for i, scanline := range *sprite.Scanlines {
+		y := i / groupsPerRow
+		xOffset := (i % groupsPerRow) * 8
 
-		for x, scanlinePixel := range scanlinePixels {
+		for x := 0; x < 8; x++ {
 			xPos := xOffset + x
 
-			if mirrorImage == true {
-				xPos = (15 - xPos)
+			// Skip the padding at the end of a row
+			if xPos >= width {
+				break
 			}
 
-			spriteImage.Set(xPos, y, (*sprite.Palette)[scanlinePixel])
+			pixels[(y*width)+xPos] = uint8((scanline >> uint(4*(7-x))) & 0xf)
 		}
 	}
 
-	draw.Draw(canvas, spriteImage.Bounds().Add(image.Pt(targetX, targetY)), spriteImage, image.ZP, draw.Over)
+	sprite.pixels = pixels
+
+	return pixels
+}
+
+// scanlineGroupsPerRow gets how many groups of 8 pixels make up each row of a
+// sprite of the given width
+func scanlineGroupsPerRow(width int) int {
+	return (width + 7) / 8
 }
@@ -12,30 +12,48 @@ type SpriteGroup struct {
 	Sprites *[]*Sprite
 }
 
-// AddToCanvas draws the sprite group on an existing image canvas
+// AddToCanvas draws the sprite group on an existing image canvas. Columns take
+// the width of the sprites in the group's first row and rows take the height
+// of the sprites in its first column, so sprites don't need to be 16x16
 func (spriteGroup *SpriteGroup) AddToCanvas(canvas *image.RGBA, targetX int, targetY int, mirrorImage bool) {
-	spriteCount := 0
-	canvasDraw := func(x int, y int) {
-		(*spriteGroup.Sprites)[spriteCount].AddToCanvas(canvas, (targetX + (x * 16)), (targetY + (y * 16)), mirrorImage)
+	spriteGroup.DrawToCanvas(canvas, targetX, targetY, DrawOptions{FlipHorizontal: mirrorImage})
+}
 
-		spriteCount++
-	}
+// DrawToCanvas draws the sprite group on an existing image canvas, transformed
+// as a whole by the draw options. Each sprite is drawn with the same options at
+// the place its cell lands once the group is transformed
+func (spriteGroup *SpriteGroup) DrawToCanvas(canvas *image.RGBA, targetX int, targetY int, options DrawOptions) {
+	columnOffsets, rowOffsets := spriteGroup.cellOffsets()
+	groupWidth := columnOffsets[spriteGroup.GroupWidth]
+	groupHeight := rowOffsets[spriteGroup.GroupHeight]
+	transform := options.transform()
 
 	for y := 0; y < spriteGroup.GroupHeight; y++ {
-		if mirrorImage == true {
+		for x := 0; x < spriteGroup.GroupWidth; x++ {
+			sprite := (*spriteGroup.Sprites)[(y*spriteGroup.GroupWidth)+x]
+			xPos, yPos := transform.rect(columnOffsets[x], rowOffsets[y], sprite.Width(), sprite.Height(), groupWidth, groupHeight)
 
-			for x := (spriteGroup.GroupWidth - 1); x >= 0; x-- {
-				canvasDraw(x, y)
-			}
+			sprite.DrawToCanvas(canvas, targetX+xPos, targetY+yPos, options)
+		}
+	}
+}
 
-		} else {
+// cellOffsets gets the pixel offset of the start of each column and row of the
+// group, with a final entry for the group's total width and height
+func (spriteGroup *SpriteGroup) cellOffsets() ([]int, []int) {
+	sprites := *spriteGroup.Sprites
+	columnOffsets := make([]int, spriteGroup.GroupWidth+1)
+	rowOffsets := make([]int, spriteGroup.GroupHeight+1)
 
-			for x := 0; x < spriteGroup.GroupWidth; x++ {
-				canvasDraw(x, y)
-			}
+	for x := 0; x < spriteGroup.GroupWidth; x++ {
+		columnOffsets[x+1] = columnOffsets[x] + sprites[x].Width()
+	}
 
-		}
+	for y := 0; y < spriteGroup.GroupHeight; y++ {
+		rowOffsets[y+1] = rowOffsets[y] + sprites[y*spriteGroup.GroupWidth].Height()
 	}
+
+	return columnOffsets, rowOffsets
 }
 
 // CreateSpriteGroup creates a sprite group based on a grid size and collection of sprites
@@ -56,10 +74,22 @@ func CreateSpriteGroup(width int, height int, sprites *[]*Sprite) (*SpriteGroup,
 
 // Width gets the pixel width of the sprite group
 func (spriteGroup *SpriteGroup) Width() int {
-	return spriteGroup.GroupWidth * 16
+	width := 0
+
+	for x := 0; x < spriteGroup.GroupWidth; x++ {
+		width += (*spriteGroup.Sprites)[x].Width()
+	}
+
+	return width
 }
 
 // Height gets the pixel height of the sprite group
 func (spriteGroup *SpriteGroup) Height() int {
-	return spriteGroup.GroupHeight * 16
+	height := 0
+
+	for y := 0; y < spriteGroup.GroupHeight; y++ {
+		height += (*spriteGroup.Sprites)[y*spriteGroup.GroupWidth].Height()
+	}
+
+	return height
 }
\ No newline at end of file
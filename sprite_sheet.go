@@ -0,0 +1,230 @@
+package engine
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+
+	// Register the PNG decoder for sprite sheets
+	_ "image/png"
+)
+
+// paletteSlots are the keys of each of the 16 entries a palette can hold
+var paletteSlots = []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "a", "b", "c", "d", "e", "f"}
+
+// SpriteSheet is a struct that defines a grid of equally sized frames that
+// has been loaded from an image at runtime, with every frame sharing one
+// palette built from the image's colours
+type SpriteSheet struct {
+	Palette     *Palette
+	FrameWidth  int
+	FrameHeight int
+	Columns     int
+	Rows        int
+	Frames      []SpriteInterface
+}
+
+// LoadSpriteSheetFile loads a sprite sheet from an image file on disk
+func LoadSpriteSheetFile(path string, frameWidth int, frameHeight int) (*SpriteSheet, error) {
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return LoadSpriteSheet(file, frameWidth, frameHeight)
+}
+
+// LoadSpriteSheetFS loads a sprite sheet from an image file in a file system,
+// such as one embedded in the game's binary
+func LoadSpriteSheetFS(fileSystem fs.FS, name string, frameWidth int, frameHeight int) (*SpriteSheet, error) {
+
+	file, err := fileSystem.Open(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return LoadSpriteSheet(file, frameWidth, frameHeight)
+}
+
+// LoadSpriteSheet decodes an image (usually a PNG) and slices it into frames
+func LoadSpriteSheet(reader io.Reader, frameWidth int, frameHeight int) (*SpriteSheet, error) {
+
+	img, _, err := image.Decode(reader)
+
+	if err != nil {
+		return nil, errors.New("Error reading sprite sheet image: " + err.Error())
+	}
+
+	return CreateSpriteSheet(img, frameWidth, frameHeight)
+}
+
+// CreateSpriteSheet slices an image into frames of the given size, reading
+// them left to right and then top to bottom. The image may use no more than 16
+// colours
+func CreateSpriteSheet(img image.Image, frameWidth int, frameHeight int) (*SpriteSheet, error) {
+
+	bounds := img.Bounds()
+
+	if frameWidth <= 0 || frameHeight <= 0 {
+		return nil, errors.New("Sprite sheet frame width and height must be greater than 0")
+	}
+
+	if bounds.Dx()%frameWidth != 0 || bounds.Dy()%frameHeight != 0 {
+		return nil, errors.New("Sprite sheet of " + strconv.Itoa(bounds.Dx()) + "x" + strconv.Itoa(bounds.Dy()) + " can't be divided into " + strconv.Itoa(frameWidth) + "x" + strconv.Itoa(frameHeight) + " frames")
+	}
+
+	palette, slots, err := createPaletteFromImage(img)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &SpriteSheet{
+		Palette:     palette,
+		FrameWidth:  frameWidth,
+		FrameHeight: frameHeight,
+		Columns:     bounds.Dx() / frameWidth,
+		Rows:        bounds.Dy() / frameHeight,
+	}
+
+	for row := 0; row < sheet.Rows; row++ {
+
+		for column := 0; column < sheet.Columns; column++ {
+
+			frame := image.Rect(0, 0, frameWidth, frameHeight).Add(bounds.Min).Add(image.Pt(column*frameWidth, row*frameHeight))
+			sprite, err := CreateSpriteWithSize(palette, frameWidth, frameHeight, encodeScanlines(img, slots, frame))
+
+			if err != nil {
+				return nil, err
+			}
+
+			sheet.Frames = append(sheet.Frames, sprite)
+		}
+	}
+
+	return sheet, nil
+}
+
+// Frame gets the frame at a column and row of the sheet
+func (sheet *SpriteSheet) Frame(column int, row int) SpriteInterface {
+	return sheet.Frames[(row*sheet.Columns)+column]
+}
+
+// RowSeries gets a sprite series that animates through every frame in a row
+// of the sheet. The series gets its own copy of the row, so changing its
+// sprites leaves the sheet alone
+func (sheet *SpriteSheet) RowSeries(row int, cyclesPerSecond int) SpriteSeries {
+
+	sprites := make([]SpriteInterface, sheet.Columns)
+	copy(sprites, sheet.Frames[row*sheet.Columns:(row+1)*sheet.Columns])
+
+	return SpriteSeries{
+		Sprites:         sprites,
+		CyclesPerSecond: cyclesPerSecond,
+	}
+}
+
+// Series gets a sprite series that animates through the frames at the given
+// indexes, counting left to right and then top to bottom
+func (sheet *SpriteSheet) Series(cyclesPerSecond int, frameIndexes ...int) SpriteSeries {
+
+	sprites := []SpriteInterface{}
+
+	for _, frameIndex := range frameIndexes {
+		sprites = append(sprites, sheet.Frames[frameIndex])
+	}
+
+	return SpriteSeries{
+		Sprites:         sprites,
+		CyclesPerSecond: cyclesPerSecond,
+	}
+}
+
+// createPaletteFromImage builds a palette from the colours in an image,
+// assigning slots in the order colours first appear. Every fully transparent
+// pixel shares a single slot
+func createPaletteFromImage(img image.Image) (*Palette, map[color.RGBA]string, error) {
+
+	bounds := img.Bounds()
+	palette := Palette{}
+	slots := map[color.RGBA]string{}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+
+			colour := imageColourAt(img, x, y)
+
+			if _, ok := slots[colour]; ok {
+				continue
+			}
+
+			if len(slots) == len(paletteSlots) {
+				return nil, nil, errors.New("More than 16 colours used in image palette")
+			}
+
+			slot := paletteSlots[len(slots)]
+			slots[colour] = slot
+			palette[slot] = colour
+		}
+	}
+
+	return &palette, slots, nil
+}
+
+// imageColourAt gets the colour of an image pixel, treating every fully
+// transparent colour as the same colour
+func imageColourAt(img image.Image, x int, y int) color.RGBA {
+
+	colour := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+
+	if colour.A == 0 {
+		return color.RGBA{}
+	}
+
+	return colour
+}
+
+// encodeScanlines encodes an area of an image as the hex-encoded scanlines
+// CreateSpriteWithSize expects, in groups of 8 pixels with each row padded out
+// to a whole group
+func encodeScanlines(img image.Image, slots map[color.RGBA]string, area image.Rectangle) []int {
+
+	groupsPerRow := scanlineGroupsPerRow(area.Dx())
+	scanlines := make([]int, 0, groupsPerRow*area.Dy())
+
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+
+		for group := 0; group < groupsPerRow; group++ {
+
+			scanline := 0
+
+			for i := 0; i < 8; i++ {
+
+				slot := int64(0)
+				x := area.Min.X + (group * 8) + i
+
+				if x < area.Max.X {
+					slot, _ = strconv.ParseInt(slots[imageColourAt(img, x, y)], 16, 0)
+				}
+
+				scanline = (scanline << 4) | int(slot)
+			}
+
+			scanlines = append(scanlines, scanline)
+		}
+	}
+
+	return scanlines
+}
@@ -0,0 +1,25 @@
+package engine
+
+import "testing"
+
+func TestRowSeriesDoesNotShareFrames(t *testing.T) {
+
+	sheet, err := CreateSpriteSheet(testSheet(), 16, 16)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nextRowFrame := sheet.Frame(0, 1)
+	spriteSeries := sheet.RowSeries(0, 1)
+	spriteSeries.Sprites = append(spriteSeries.Sprites, testSprite(t))
+	spriteSeries.Sprites[0] = testSprite(t)
+
+	if sheet.Frame(0, 1) != nextRowFrame || sheet.Frame(0, 0) == spriteSeries.Sprites[0] {
+		t.Fatal("expected changing a row's series to leave the sheet's frames alone")
+	}
+
+	if len(sheet.RowSeries(1, 1).Sprites) != sheet.Columns {
+		t.Fatalf("expected a series of %d frames for the second row", sheet.Columns)
+	}
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+// uncachedSprite draws a sprite the way sprites were drawn before rasters
+// were cached, decoding its scanlines on every draw
+type uncachedSprite struct {
+	*Sprite
+}
+
+// AddToCanvas draws the sprite without the raster cache
+func (sprite uncachedSprite) AddToCanvas(canvas *image.RGBA, targetX int, targetY int, mirrorImage bool) {
+
+	if targetX+sprite.Width() < 0 || targetX > canvas.Bounds().Max.X || targetY+sprite.Height() < 0 || targetY > canvas.Bounds().Max.Y {
+		return
+	}
+
+	spriteImage := image.NewRGBA(image.Rect(0, 0, 16, 16))
+
+	for i, scanlines := range *sprite.Scanlines {
+
+		y := i
+		xOffset := 0
+
+		if (i % 2) != 0 {
+			y--
+			xOffset = 8
+		}
+
+		y /= 2
+
+		for x, scanlinePixel := range strings.Split(fmt.Sprintf("%08x", scanlines), "") {
+
+			xPos := xOffset + x
+
+			if mirrorImage == true {
+				xPos = (15 - xPos)
+			}
+
+			spriteImage.Set(xPos, y, (*sprite.Palette)[scanlinePixel])
+		}
+	}
+
+	draw.Draw(canvas, spriteImage.Bounds().Add(image.Pt(targetX, targetY)), spriteImage, image.ZP, draw.Over)
+}
+
+// DrawToCanvas draws the sprite without the raster cache, supporting only
+// horizontal flips
+func (sprite uncachedSprite) DrawToCanvas(canvas *image.RGBA, targetX int, targetY int, options DrawOptions) {
+	sprite.AddToCanvas(canvas, targetX, targetY, options.FlipHorizontal)
+}
+
+// exampleSizedGame builds a game whose level has as many sprites as the
+// example's, wrapping each sprite before it is drawn
+func exampleSizedGame(tb testing.TB, wrap func(sprite *Sprite) SpriteInterface) *Game {
+
+	gameObjects := []*GameObject{}
+
+	// Clouds of 3x2 sprites, which are only scenery
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 6; j++ {
+			cloud := testObject(wrap(testSprite(tb)), float64((i*150)+((j%3)*16)), float64(150+((j/3)*16)))
+			cloud.IsInteractive = false
+			gameObjects = append(gameObjects, cloud)
+		}
+	}
+
+	// Floor tiles
+	for i := 0; i < 80; i++ {
+		tile := testObject(wrap(testSprite(tb)), float64(i*16), 0)
+		tile.IsFloor = true
+		gameObjects = append(gameObjects, tile)
+	}
+
+	// A 2x3 character
+	for j := 0; j < 6; j++ {
+		gameObjects = append(gameObjects, testObject(wrap(testSprite(tb)), float64(20+((j%2)*16)), float64(16+((j/2)*16))))
+	}
+
+	level := &Level{
+		BackgroundColour: color.RGBA{126, 192, 238, 255},
+		GameObjects:      gameObjects,
+	}
+
+	game := NewGame("Benchmark", 320, 224, 1, 60, func(stage *image.RGBA, level *Level, frameRate float64) {}, nil, []*Level{level})
+	game.Backend = &HeadlessBackend{Frames: 1}
+
+	return game
+}
+
+func TestCachedRasterMatchesFreshRaster(t *testing.T) {
+
+	palette := Palette{}
+
+	for slot, colour := range *testPalette {
+		palette[slot] = colour
+	}
+
+	sprite := testSprite(t)
+	sprite.Palette = &palette
+
+	for _, mirrorImage := range []bool{false, true, false, true} {
+
+		// Change a colour part way through, so the cache has to notice
+		if len(sprite.rasters) == 2 {
+			palette["2"] = color.RGBA{255, 255, 0, 255}
+		}
+
+		cached := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		sprite.AddToCanvas(cached, 0, 0, mirrorImage)
+
+		fresh := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		freshSprite := &Sprite{Palette: &palette, Scanlines: sprite.Scanlines}
+		freshSprite.AddToCanvas(fresh, 0, 0, mirrorImage)
+
+		uncached := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		uncachedSprite{freshSprite}.AddToCanvas(uncached, 0, 0, mirrorImage)
+
+		for i := range cached.Pix {
+
+			if cached.Pix[i] != fresh.Pix[i] || cached.Pix[i] != uncached.Pix[i] {
+				t.Fatalf("cached raster (mirrored %v) differs at pixel %d: cached %v, fresh %v, uncached %v", mirrorImage, i/4, cached.Pix[i], fresh.Pix[i], uncached.Pix[i])
+			}
+		}
+	}
+
+	if len(sprite.rasters) != 4 {
+		t.Fatalf("expected 4 cached rasters (2 orientations in 2 sets of colours), got %d", len(sprite.rasters))
+	}
+}
+
+func BenchmarkSpriteAddToCanvas(b *testing.B) {
+
+	sprite := testSprite(b)
+	canvas := image.NewRGBA(image.Rect(0, 0, 320, 224))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sprite.AddToCanvas(canvas, 100, 100, i%2 == 0)
+	}
+}
+
+func BenchmarkSpriteAddToCanvasUncached(b *testing.B) {
+
+	sprite := uncachedSprite{testSprite(b)}
+	canvas := image.NewRGBA(image.Rect(0, 0, 320, 224))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sprite.AddToCanvas(canvas, 100, 100, i%2 == 0)
+	}
+}
+
+func BenchmarkLevelRepaint(b *testing.B) {
+
+	game := exampleSizedGame(b, func(sprite *Sprite) SpriteInterface { return sprite })
+	game.Step()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		game.Step()
+	}
+}
+
+func BenchmarkLevelRepaintUncached(b *testing.B) {
+
+	game := exampleSizedGame(b, func(sprite *Sprite) SpriteInterface { return uncachedSprite{sprite} })
+	game.Step()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		game.Step()
+	}
+}
@@ -0,0 +1,150 @@
+package engine
+
+// StateMachine is a struct that defines the states a game object can be in and
+// the transitions allowed between them. State machines hold nothing about the
+// game objects using them, so one machine can be shared by many game objects
+type StateMachine struct {
+	InitialState string
+	States       map[string]MachineState
+	Transitions  []StateTransition
+}
+
+// MachineState is a struct that defines the hooks called as a game object
+// enters, exits and updates in a state. Series names the sprite series from
+// the game object's States that is shown in the state, which defaults to the
+// series with the same name as the state
+type MachineState struct {
+	Series   string
+	OnEnter  StateHook
+	OnExit   StateHook
+	OnUpdate StateHook
+}
+
+// StateTransition is a struct that defines a change allowed from one state to
+// another, with From set to AnyState allowing it from every state. Transitions
+// with a guard are only allowed while the guard passes. Automatic transitions
+// are taken during a level update as soon as they are allowed, while the rest
+// have to be asked for with ChangeState
+type StateTransition struct {
+	From        string
+	To          string
+	Guard       StateGuard
+	IsAutomatic bool
+}
+
+// ChangeState moves the game object to another state, calling the exit hook of
+// the state it leaves and the enter hook of the state it enters. Game objects
+// with a state machine only change state if a transition allows it, and the
+// result reports whether the state was changed. Machines that haven't started
+// yet enter their initial state first
+func (gameObject *GameObject) ChangeState(state string) bool {
+
+	if gameObject.StateMachine == nil {
+		gameObject.CurrentState = state
+		return true
+	}
+
+	gameObject.startStateMachine()
+
+	for _, transition := range gameObject.StateMachine.Transitions {
+
+		if transition.To == state && transition.allows(gameObject) == true {
+			gameObject.enterState(state)
+			return true
+		}
+	}
+
+	return false
+}
+
+// allows determines whether the transition can be taken by the game object
+// from its current state
+func (transition StateTransition) allows(gameObject *GameObject) bool {
+
+	if transition.From != AnyState && transition.From != gameObject.CurrentState {
+		return false
+	}
+
+	return transition.Guard == nil || transition.Guard(gameObject) == true
+}
+
+// updateStateMachine runs the game object's state machine for a tick, starting
+// it if it hasn't been started, calling the current state's update hook and
+// then taking the first automatic transition that is allowed. States set
+// directly on CurrentState are entered and exited like any other change
+func (gameObject *GameObject) updateStateMachine() {
+
+	stateMachine := gameObject.StateMachine
+	gameObject.startStateMachine()
+
+	if gameObject.CurrentState != gameObject.machineState {
+		gameObject.enterState(gameObject.CurrentState)
+	}
+
+	if onUpdate := stateMachine.States[gameObject.CurrentState].OnUpdate; onUpdate != nil {
+		onUpdate(gameObject)
+	}
+
+	for _, transition := range stateMachine.Transitions {
+
+		if transition.IsAutomatic == true && transition.To != gameObject.CurrentState && transition.allows(gameObject) == true {
+			gameObject.enterState(transition.To)
+			break
+		}
+	}
+}
+
+// startStateMachine enters the state machine's initial state (or the game
+// object's current state if it has none) the first time the machine is used,
+// whether by a level update or an earlier ChangeState
+func (gameObject *GameObject) startStateMachine() {
+
+	if gameObject.machineStarted == true {
+		return
+	}
+
+	gameObject.machineStarted = true
+	state := gameObject.StateMachine.InitialState
+
+	if state == "" {
+		state = gameObject.CurrentState
+	}
+
+	gameObject.enterState(state)
+}
+
+// enterState exits the state the game object's state machine last entered and
+// enters a new one
+func (gameObject *GameObject) enterState(state string) {
+
+	if gameObject.StateMachine == nil {
+		gameObject.CurrentState = state
+		return
+	}
+
+	states := gameObject.StateMachine.States
+
+	if onExit := states[gameObject.machineState].OnExit; gameObject.machineState != "" && onExit != nil {
+		onExit(gameObject)
+	}
+
+	gameObject.CurrentState = state
+	gameObject.machineState = state
+
+	if onEnter := states[state].OnEnter; onEnter != nil {
+		onEnter(gameObject)
+	}
+}
+
+// currentSeries gets the sprite series shown in the game object's current
+// state, which a state machine may map to a series with a different name
+func (gameObject *GameObject) currentSeries() SpriteSeries {
+
+	if gameObject.StateMachine != nil {
+		if series := gameObject.StateMachine.States[gameObject.CurrentState].Series; series != "" {
+			return gameObject.States[series]
+		}
+	}
+
+	return gameObject.States[gameObject.CurrentState]
+}
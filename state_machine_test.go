@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// machineObject creates a game object with a state machine that can walk
+// while canWalk is set, stops automatically when it isn't and can be hurt from
+// any state, logging every hook called
+func machineObject(t *testing.T, canWalk *bool, hooks *[]string) *GameObject {
+
+	sprite := testSprite(t)
+	gameObject := testObject(sprite, 0, 0)
+	states := map[string]MachineState{}
+
+	for _, state := range []string{"idle", "walk", "hurt"} {
+
+		state := state
+		gameObject.States[state] = SpriteSeries{Sprites: []SpriteInterface{sprite}}
+		states[state] = MachineState{
+			OnEnter: func(gameObject *GameObject) {
+				*hooks = append(*hooks, "enter "+state)
+			},
+			OnExit: func(gameObject *GameObject) {
+				*hooks = append(*hooks, "exit "+state)
+			},
+			OnUpdate: func(gameObject *GameObject) {
+				*hooks = append(*hooks, "update "+state)
+			},
+		}
+	}
+
+	gameObject.StateMachine = &StateMachine{
+		InitialState: "idle",
+		States:       states,
+		Transitions: []StateTransition{
+			{From: "idle", To: "walk", Guard: func(gameObject *GameObject) bool { return *canWalk }},
+			{From: "walk", To: "idle", Guard: func(gameObject *GameObject) bool { return *canWalk == false }, IsAutomatic: true},
+			{From: AnyState, To: "hurt"},
+		},
+	}
+
+	return gameObject
+}
+
+func TestStateMachineTransitions(t *testing.T) {
+
+	canWalk := false
+	hooks := []string{}
+	gameObject := machineObject(t, &canWalk, &hooks)
+
+	gameObject.updateStateMachine()
+
+	if gameObject.ChangeState("walk") == true || gameObject.CurrentState != "idle" {
+		t.Fatal("expected the guard to stop the object walking")
+	}
+
+	canWalk = true
+
+	if gameObject.ChangeState("walk") == false || gameObject.CurrentState != "walk" {
+		t.Fatal("expected the object to walk once the guard passes")
+	}
+
+	// Stopping is automatic once the object can't walk
+	canWalk = false
+	gameObject.updateStateMachine()
+
+	if gameObject.CurrentState != "idle" {
+		t.Fatalf("expected the object to stop walking automatically, it is %s", gameObject.CurrentState)
+	}
+
+	if gameObject.ChangeState("hurt") == false || gameObject.ChangeState("walk") == true {
+		t.Fatal("expected the object to be hurt from any state, but not walk from hurt")
+	}
+
+	expected := []string{
+		"enter idle", "update idle",
+		"exit idle", "enter walk",
+		"update walk", "exit walk", "enter idle",
+		"exit idle", "enter hurt",
+	}
+
+	if reflect.DeepEqual(hooks, expected) == false {
+		t.Fatalf("expected hooks %v, got %v", expected, hooks)
+	}
+}
+
+func TestStateChangesBeforeFirstUpdateAreKept(t *testing.T) {
+
+	canWalk := true
+	hooks := []string{}
+	gameObject := machineObject(t, &canWalk, &hooks)
+
+	// Changes made before the first tick (such as from a level's OnEnter)
+	// start from the initial state
+	if gameObject.ChangeState("walk") == false {
+		t.Fatal("expected the object to walk from its initial state")
+	}
+
+	gameObject.updateStateMachine()
+
+	if gameObject.CurrentState != "walk" {
+		t.Fatalf("expected the object to still be walking after the first update, it is %s", gameObject.CurrentState)
+	}
+
+	if expected := []string{"enter idle", "exit idle", "enter walk", "update walk"}; reflect.DeepEqual(hooks, expected) == false {
+		t.Fatalf("expected hooks %v, got %v", expected, hooks)
+	}
+}
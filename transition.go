@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
+
+// TransitionEffect is an interface that defines effects that are drawn over
+// the stage while a screen transition is in progress, where progress runs
+// from 0 at the start of the transition to 1 at the end
+type TransitionEffect interface {
+	Composite(stage *image.RGBA, level *Level, progress float64)
+}
+
+// ScreenTransition is a struct that defines a transition effect that runs for
+// a duration before calling its completion callback. Transitions without an
+// effect draw nothing over the stage while they wait
+type ScreenTransition struct {
+	Effect     TransitionEffect
+	Duration   time.Duration
+	OnComplete func(game *Game)
+	elapsed    time.Duration
+}
+
+// FadeTransition is a struct that defines an effect that fades the stage out
+// to a colour, or in from it when FadeIn is set
+type FadeTransition struct {
+	Colour color.RGBA
+	FadeIn bool
+}
+
+// WipeTransition is a struct that defines an effect that covers the stage with
+// a colour from one side to the other, or uncovers it when WipeIn is set. The
+// direction is given by DirLeft or DirRight
+type WipeTransition struct {
+	Colour    color.RGBA
+	Direction int
+	WipeIn    bool
+}
+
+// IrisTransition is a struct that defines an effect that closes a circle of
+// colour in on a target game object, or opens out from it when Open is set.
+// The circle is centred on the stage if there is no target
+type IrisTransition struct {
+	Colour color.RGBA
+	Target *GameObject
+	Open   bool
+}
+
+// StartTransition starts drawing a screen transition over every frame until it
+// completes, replacing any transition already in progress
+func (game *Game) StartTransition(transition *ScreenTransition) {
+
+	transition.elapsed = 0
+	game.transition = transition
+}
+
+// IsTransitioning determines whether a screen transition is in progress
+func (game *Game) IsTransitioning() bool {
+	return game.transition != nil
+}
+
+// TransitionToLevel plays the out effect, moves to the given level and then
+// plays the in effect, with each effect lasting for the given duration. Either
+// effect can be nil to skip drawing it. Levels are checked up front, so an
+// error moving to the level once the out effect finishes (such as the level
+// having been removed since) is logged
+func (game *Game) TransitionToLevel(levelID int, duration time.Duration, out TransitionEffect, in TransitionEffect) error {
+
+	if levelID < 0 || levelID >= len(game.Levels) {
+		return errors.New("Level " + strconv.Itoa(levelID) + " does not exist")
+	}
+
+	game.StartTransition(&ScreenTransition{
+		Effect:   out,
+		Duration: duration,
+		OnComplete: func(game *Game) {
+
+			if err := game.GoToLevel(levelID); err != nil {
+				log.Println("Error transitioning to level: " + err.Error())
+			}
+
+			game.StartTransition(&ScreenTransition{
+				Effect:   in,
+				Duration: duration,
+			})
+		},
+	})
+
+	return nil
+}
+
+// compositeTransition draws the transition in progress over the stage,
+// finishing it once its duration has elapsed
+func (game *Game) compositeTransition(stage *image.RGBA, elapsed time.Duration) {
+
+	transition := game.transition
+
+	if transition == nil {
+		return
+	}
+
+	transition.elapsed += elapsed
+	progress := 1.0
+
+	if transition.Duration > 0 && transition.elapsed < transition.Duration {
+		progress = float64(transition.elapsed) / float64(transition.Duration)
+	}
+
+	if transition.Effect != nil {
+		transition.Effect.Composite(stage, game.CurrentLevel(), progress)
+	}
+
+	if progress < 1 {
+		return
+	}
+
+	game.transition = nil
+
+	if transition.OnComplete != nil {
+		transition.OnComplete(game)
+	}
+}
+
+// Composite draws the fade over the stage
+func (effect *FadeTransition) Composite(stage *image.RGBA, level *Level, progress float64) {
+
+	opacity := progress
+
+	if effect.FadeIn == true {
+		opacity = 1 - progress
+	}
+
+	mask := &image.Uniform{color.Alpha{uint8(math.Round(opacity * 255))}}
+
+	draw.DrawMask(stage, stage.Bounds(), &image.Uniform{effect.Colour}, image.ZP, mask, image.ZP, draw.Over)
+}
+
+// Composite draws the wipe over the stage
+func (effect *WipeTransition) Composite(stage *image.RGBA, level *Level, progress float64) {
+
+	bounds := stage.Bounds()
+	edge := int(math.Round(progress * float64(bounds.Dx())))
+	covered := image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Min.X+edge, bounds.Max.Y)
+
+	// Wiping in uncovers the stage in the same direction it was covered
+	if effect.WipeIn == true {
+		covered = image.Rect(bounds.Min.X+edge, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
+	}
+
+	if effect.Direction == DirLeft {
+		covered = image.Rect(bounds.Max.X-(covered.Max.X-bounds.Min.X), bounds.Min.Y, bounds.Max.X-(covered.Min.X-bounds.Min.X), bounds.Max.Y)
+	}
+
+	draw.Draw(stage, covered, &image.Uniform{effect.Colour}, image.ZP, draw.Src)
+}
+
+// Composite draws the iris over the stage
+func (effect *IrisTransition) Composite(stage *image.RGBA, level *Level, progress float64) {
+
+	bounds := stage.Bounds()
+	centreX := float64(bounds.Min.X+bounds.Max.X) / 2
+	centreY := float64(bounds.Min.Y+bounds.Max.Y) / 2
+
+	if effect.Target != nil && level != nil && level.Game != nil {
+		paintPosition := level.PaintPosition(effect.Target)
+		centreX = float64(paintPosition.X) + (float64(effect.Target.Width()) / 2)
+		centreY = float64(paintPosition.Y) + (float64(effect.Target.Height()) / 2)
+	}
+
+	// The iris is fully open once it reaches the corner furthest from its centre
+	maxRadius := 0.0
+
+	for _, corner := range []image.Point{bounds.Min, {bounds.Max.X, bounds.Min.Y}, {bounds.Min.X, bounds.Max.Y}, bounds.Max} {
+		maxRadius = math.Max(maxRadius, math.Hypot(float64(corner.X)-centreX, float64(corner.Y)-centreY))
+	}
+
+	radius := (1 - progress) * maxRadius
+
+	if effect.Open == true {
+		radius = progress * maxRadius
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+
+			if math.Hypot(float64(x)+0.5-centreX, float64(y)+0.5-centreY) > radius {
+				stage.SetRGBA(x, y, effect.Colour)
+			}
+		}
+	}
+}
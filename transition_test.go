@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransitionsWithoutEffects(t *testing.T) {
+
+	game := NewGame("Transitions", 64, 64, 1, 60, nil, nil, []*Level{{}, {}})
+	stage := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	completions := 0
+
+	game.StartTransition(&ScreenTransition{
+		Duration: time.Second,
+		OnComplete: func(game *Game) {
+			completions++
+		},
+	})
+	game.compositeTransition(stage, time.Second)
+
+	if completions != 1 || game.IsTransitioning() == true {
+		t.Fatal("expected a transition without an effect to complete")
+	}
+
+	if err := game.TransitionToLevel(1, time.Second, nil, &FadeTransition{FadeIn: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	game.compositeTransition(stage, time.Second)
+
+	if game.CurrentLevelID != 1 || game.IsTransitioning() == false {
+		t.Fatal("expected the game to move to level 1 and fade in")
+	}
+
+	// Without an out effect nothing has been drawn on the stage
+	if stage.RGBAAt(0, 0) != (color.RGBA{}) {
+		t.Fatalf("expected the stage to be left alone, got %v", stage.RGBAAt(0, 0))
+	}
+
+	game.compositeTransition(stage, time.Second)
+
+	if game.IsTransitioning() == true {
+		t.Fatal("expected the fade in to complete")
+	}
+}
+
+func TestTransitionToRemovedLevelIsLogged(t *testing.T) {
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	game := NewGame("Transitions", 64, 64, 1, 60, nil, nil, []*Level{{}, {}})
+	stage := image.NewRGBA(image.Rect(0, 0, 64, 64))
+
+	if err := game.TransitionToLevel(1, time.Second, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	game.Levels = game.Levels[:1]
+	game.compositeTransition(stage, time.Second)
+
+	if game.CurrentLevelID != 0 || strings.Contains(logged.String(), "Level 1 does not exist") == false {
+		t.Fatalf("expected moving to a removed level to be logged, got %q", logged.String())
+	}
+}
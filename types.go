@@ -3,6 +3,7 @@ package engine
 import (
 	"image"
 	"image/color"
+	"time"
 
 	"golang.org/x/mobile/event/key"
 )
@@ -11,20 +12,47 @@ import (
 type Palette map[string]color.RGBA
 
 // SpriteSeries is a type that defines a series of sprites
-// that form an animation for a game object state
+// that form an animation for a game object state, optionally with a hitbox
+// that replaces the game object's own while it is in that state. Frames are
+// shown for an even share of a cycle unless given their own FrameDurations.
+// Series loop forever unless their PlayMode plays them once or their
+// LoopCount limits the cycles, after which OnComplete is called and the game
+// object moves on to NextState (if set and allowed by its state machine).
+// FrameEvents names events that are emitted as particular frames are reached
 type SpriteSeries struct {
 	Sprites []SpriteInterface
 	CyclesPerSecond int
+	FrameDurations []time.Duration
+	Hitbox *Hitbox
+	PlayMode int
+	LoopCount int
+	OnComplete AnimationHandler
+	NextState string
+	FrameEvents map[int]string
+}
+
+// Hitbox is a struct that defines the area of a game object that takes part in
+// floor and collision checks, offset from the bottom left corner of its sprite.
+// Hitboxes are given in the sprite's own pixels, before it is transformed
+type Hitbox struct {
+	Offset Vector
+	Width float64
+	Height float64
 }
 
 // SpriteInterface is an interface that defines objects that can be
-// treated as a single sprites
+// treated as a single sprites. Width and Height are the untransformed size
 type SpriteInterface interface {
 	AddToCanvas(canvas *image.RGBA, targetX int, targetY int, mirrorImage bool)
+	DrawToCanvas(canvas *image.RGBA, targetX int, targetY int, options DrawOptions)
 	Width() int
 	Height() int
 }
 
+// PaletteFilter is the signature for functions that adjust the colours of a
+// palette as sprites are drawn
+type PaletteFilter func(colour color.RGBA) color.RGBA
+
 // GameObjectStates is a type that defines the various states (and accompanying
 // sprites) of game objects
 type GameObjectStates map[string]SpriteSeries
@@ -39,6 +67,22 @@ type KeyListener func(event key.Event, gameObject *GameObject)
 // EventHandler is the signature for functions that handle game events
 type EventHandler func(eventCode int, gameObject *GameObject)
 
+// AnimationHandler is the signature for functions that are called when a
+// game object's animation finishes
+type AnimationHandler func(gameObject *GameObject)
+
+// AnimationEventHandler is the signature for functions that handle the named
+// events emitted as animations reach particular frames
+type AnimationEventHandler func(eventName string, gameObject *GameObject)
+
+// StateHook is the signature for functions that are called as a game object
+// enters, exits or updates in a state machine state
+type StateHook func(gameObject *GameObject)
+
+// StateGuard is the signature for functions that decide whether a game object
+// may take a state transition
+type StateGuard func(gameObject *GameObject) bool
+
 // CollisionHandler is a signature for functions that handle collision events
 type CollisionHandler func(gameObject *GameObject, collision Collision)
 
@@ -46,6 +90,14 @@ type CollisionHandler func(gameObject *GameObject, collision Collision)
 // to them being repainted
 type BeforePaint func(level *Level)
 
+// LevelHook is the signature for functions that are called on levels as they
+// are entered or exited
+type LevelHook func(level *Level)
+
+// LevelFactory is the signature for functions that build a fresh copy of a
+// level
+type LevelFactory func() *Level
+
 // Vector is a struct to represent X/Y vectors
 type Vector struct {
 	X float64
@@ -56,8 +108,11 @@ type Vector struct {
 // data
 type DynamicData map[string]interface{}
 
-// Collision is a struct that represents a collision with another game object
+// Collision is a struct that represents a collision with another game object.
+// TimeOfImpact is the fraction of the tick's movement that had been made when
+// a swept collision happened, and is zero for overlapping objects
 type Collision struct {
 	GameObject *GameObject
 	Edge string
+	TimeOfImpact float64
 }
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"image"
 	"image/draw"
 	"time"
@@ -13,9 +14,23 @@ import (
 	"golang.org/x/mobile/event/paint"
 )
 
+// WindowBackend is a struct that defines a backend that runs a game inside a
+// desktop window, blocking until the window is closed
+type WindowBackend struct{}
+
+// stopEvent is sent to the window's event queue when the game is stopped
+type stopEvent struct{}
+
+// Run opens the game window and paints frames until it is closed or the
+// context is cancelled
+func (backend *WindowBackend) Run(ctx context.Context, game *Game) error {
+	return createWindow(ctx, game)
+}
 
 // createWindow creates a window and provides a corresponding image that can be drawn on
-func createWindow(game *Game) {
+func createWindow(ctx context.Context, game *Game) error {
+
+	var windowErr error
 
 	lastPaintTimeNano := time.Now().UnixNano()
 	targetFrameAgeNano := int64(1000000000) / int64(game.TargetFrameRate)
@@ -24,8 +39,29 @@ func createWindow(game *Game) {
 	driver.Main(func(src screen.Screen) {
 
 		res := image.Pt(game.Width*game.ScaleFactor, game.Height*game.ScaleFactor)
-		win, _ := src.NewWindow(&screen.NewWindowOptions{Width: res.X, Height: res.Y, Title: game.Title})
-		buf, _ := src.NewBuffer(res)
+		win, err := src.NewWindow(&screen.NewWindowOptions{Width: res.X, Height: res.Y, Title: game.Title})
+
+		if err != nil {
+			windowErr = err
+			return
+		}
+
+		defer win.Release()
+
+		buf, err := src.NewBuffer(res)
+
+		if err != nil {
+			windowErr = err
+			return
+		}
+
+		defer buf.Release()
+
+		// Wake the event loop up when the game is stopped
+		go func() {
+			<-ctx.Done()
+			win.Send(stopEvent{})
+		}()
 
 		for {
 
@@ -38,6 +74,10 @@ func createWindow(game *Game) {
 					return
 				}
 
+				// Game stopped from code
+			case stopEvent:
+				return
+
 				// Window repaints
 			case paint.Event:
 
@@ -49,22 +89,13 @@ func createWindow(game *Game) {
 					frameAgeNano = targetFrameAgeNano
 				}
 
-				game.CurrentFrame++
-
-				if game.CurrentFrame > game.TargetFrameRate {
-					game.CurrentFrame = 1
-				}
-
 				frameAgeSeconds := (float64(frameAgeNano) / float64(1000000000))
 				currentFrameRate := 1 / frameAgeSeconds
 
 				// Repaint the stage
 				lastPaintTimeNano = time.Now().UnixNano()
-				stage := image.NewRGBA(image.Rect(0, 0, game.Width, game.Height))
+				stage := game.renderFrame(time.Duration(frameAgeNano), currentFrameRate)
 
-				game.CurrentLevel().BeforePaint(game.CurrentLevel())
-				game.CurrentLevel().Repaint(stage)
-				game.FramePainter(stage, game.CurrentLevel(), currentFrameRate)
 				xdraw.NearestNeighbor.Scale(buf.RGBA(), image.Rect(0, 0, game.Width*game.ScaleFactor, game.Height*game.ScaleFactor), stage, stage.Bounds(), draw.Over, nil)
 				win.Upload(image.Point{}, buf, buf.Bounds())
 				win.Publish()
@@ -80,4 +111,5 @@ func createWindow(game *Game) {
 
 	})
 
+	return windowErr
 }
\ No newline at end of file